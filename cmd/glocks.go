@@ -2,9 +2,24 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"runtime"
+	"strings"
+	"time"
 
+	"github.com/levpaul/glocks/internal/analysis"
+	"github.com/levpaul/glocks/internal/bench"
+	"github.com/levpaul/glocks/internal/builtins"
+	"github.com/levpaul/glocks/internal/coverage"
+	"github.com/levpaul/glocks/internal/debugger"
 	"github.com/levpaul/glocks/internal/interpreter"
+	"github.com/levpaul/glocks/internal/lexer"
+	"github.com/levpaul/glocks/internal/lsp"
+	"github.com/levpaul/glocks/internal/parser"
+	"github.com/levpaul/glocks/internal/profiler"
+	"github.com/levpaul/glocks/internal/testrunner"
+	"github.com/levpaul/glocks/internal/trace"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
@@ -24,30 +39,139 @@ func execute() {
 	defer rawLogger.Sync() // flushes buffer, if any
 	log := rawLogger.Sugar()
 
-	glocksI := interpreter.New(log)
+	var allow []string
+	var profile bool
+	var profileOut string
+	var cover bool
+	var coverOut string
+	var optimize bool
+	var memoryLimit int64
+	var memoryStats bool
+	var traceOn bool
+	var traceFormat string
+	var traceOut string
 	var rootCmd = &cobra.Command{
 		Use:           "glocks",
 		Short:         "glocks <file> run <file> or open the glocks REPL",
 		SilenceUsage:  true,
 		SilenceErrors: true,
+		Args:          cobra.ArbitraryArgs, // allows for a file arg alongside subcommands
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) > 1 {
 				log.Error("Expected maximum of 1 arg - received '", args, "' - exiting 1")
 				return errors.New("too many args")
 			}
 
-			if len(args) == 1 {
-				program, err := os.ReadFile(args[0])
-				if err != nil {
+			caps, err := parseAllowFlag(log, allow)
+			if err != nil {
+				return err
+			}
+			opts := []interpreter.Option{interpreter.WithCapabilities(caps...)}
+			if optimize {
+				opts = append(opts, interpreter.WithOptimization())
+			}
+			if memoryLimit > 0 {
+				opts = append(opts, interpreter.WithMemoryLimit(memoryLimit))
+			}
+
+			var program []byte
+			if len(args) > 0 {
+				if program, err = os.ReadFile(args[0]); err != nil {
 					log.With("error", err).Errorf("Failed to read file '%s' from disk\n", args[0])
 					return err
 				}
-				return glocksI.Run(string(program))
 			}
 
-			return glocksI.REPL()
+			if traceOn {
+				tracer, closeTrace, err := newTracer(log, traceFormat, traceOut, string(program))
+				if err != nil {
+					return err
+				}
+				defer closeTrace()
+				opts = append(opts, interpreter.WithStatementHook(tracer), interpreter.WithCallHook(tracer),
+					interpreter.WithAssignHook(tracer))
+			}
+
+			if len(args) == 0 {
+				if profile {
+					log.Error("--profile profiles a script run, and can't be used with the REPL - exiting 1")
+					return errors.New("--profile requires a file")
+				}
+				if cover {
+					log.Error("--coverage records a script run, and can't be used with the REPL - exiting 1")
+					return errors.New("--coverage requires a file")
+				}
+				if memoryStats {
+					log.Error("--memory-stats reports on a script run, and can't be used with the REPL - exiting 1")
+					return errors.New("--memory-stats requires a file")
+				}
+				return interpreter.New(log, opts...).REPL()
+			}
+
+			var prof *profiler.Profiler
+			if profile {
+				prof = profiler.New(args[0])
+				opts = append(opts, interpreter.WithCallHook(prof))
+			}
+			var recorder *coverage.Recorder
+			if cover {
+				if recorder, err = coverage.New(args[0], string(program), log); err != nil {
+					log.With("error", err).Errorf("Failed to parse file '%s' for coverage\n", args[0])
+					return err
+				}
+				opts = append(opts, interpreter.WithStatementHook(recorder), interpreter.WithBranchHook(recorder))
+			}
+			glocksI := interpreter.New(log, opts...)
+
+			if prof != nil {
+				prof.Start()
+			}
+			runErr := glocksI.Run(string(program))
+			if memoryStats {
+				fmt.Fprintf(os.Stderr, "Peak memory: %d bytes\n", glocksI.PeakMemory())
+			}
+			if prof != nil {
+				prof.Stop()
+				if err := writeProfile(log, prof, profileOut); err != nil {
+					return err
+				}
+			}
+			if recorder != nil {
+				if err := writeCoverage(log, recorder, coverOut); err != nil {
+					return err
+				}
+			}
+			return runErr
 		},
 	}
+	rootCmd.Flags().BoolVarP(&optimize, "optimize", "O", false,
+		"fold constant expressions and remove code which can never run before running the script")
+	rootCmd.Flags().Int64Var(&memoryLimit, "memory-limit", 0,
+		"fail the script once it has more than this many bytes (approximately) in use, or 0 for no limit")
+	rootCmd.Flags().BoolVar(&memoryStats, "memory-stats", false,
+		"print the most memory, in approximate bytes, the script had in use to stderr once it finishes")
+	rootCmd.Flags().BoolVar(&profile, "profile", false,
+		"profile the time spent in each Lox function, printing a report to stderr and writing a pprof profile")
+	rootCmd.Flags().StringVar(&profileOut, "profile-out", "glocks.pprof", "where --profile writes the pprof profile")
+	rootCmd.Flags().BoolVar(&cover, "coverage", false,
+		"record which statements and branches run, printing a summary to stderr and writing an lcov report")
+	rootCmd.Flags().StringVar(&coverOut, "coverage-out", "coverage.lcov", "where --coverage writes the lcov report")
+	rootCmd.Flags().BoolVar(&traceOn, "trace", false,
+		"log every statement, call, return and assignment as the script runs")
+	rootCmd.Flags().StringVar(&traceFormat, "trace-format", string(trace.Text),
+		"format of the --trace log, either text or json (one JSON object per line)")
+	rootCmd.Flags().StringVar(&traceOut, "trace-out", "", "file --trace writes to, rather than stderr")
+
+	rootCmd.PersistentFlags().StringSliceVar(&allow, "allow", capabilityNames(builtins.DefaultCapabilities),
+		"capabilities granted to scripts, from: all, "+strings.Join(capabilityNames(builtins.Capabilities()), ", "))
+
+	rootCmd.AddCommand(newLintCmd(log))
+	rootCmd.AddCommand(newLSPCmd(log))
+	rootCmd.AddCommand(newDebugCmd(log, &allow))
+	rootCmd.AddCommand(newTestCmd(log, &allow))
+	rootCmd.AddCommand(newBenchCmd(log, &allow))
+	rootCmd.AddCommand(newASTCmd(log))
+	rootCmd.AddCommand(newTokensCmd(log))
 
 	if err := rootCmd.Execute(); err != nil {
 		// Cobra logic is expected to print human friendly error
@@ -56,3 +180,301 @@ func execute() {
 	}
 
 }
+
+// capabilityNames returns the names of capabilities, as they are given to the --allow flag
+func capabilityNames(caps []builtins.Capability) []string {
+	names := make([]string, len(caps))
+	for idx, c := range caps {
+		names[idx] = string(c)
+	}
+	return names
+}
+
+// writeProfile prints the report of a profile to stderr and writes it in pprof format to path
+func writeProfile(log *zap.SugaredLogger, prof *profiler.Profiler, path string) error {
+	prof.WriteReport(os.Stderr)
+
+	f, err := os.Create(path)
+	if err != nil {
+		log.With("error", err).Errorf("Failed to create profile '%s'\n", path)
+		return err
+	}
+	defer f.Close()
+	if err := prof.WritePprof(f); err != nil {
+		log.With("error", err).Errorf("Failed to write profile '%s'\n", path)
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "\nWrote pprof profile to %s, view it with: go tool pprof -top %s\n", path, path)
+	return nil
+}
+
+// writeCoverage prints a summary of coverage to stderr and writes it as an lcov report to path
+func writeCoverage(log *zap.SugaredLogger, recorder *coverage.Recorder, path string) error {
+	fmt.Fprintln(os.Stderr, recorder.Summary())
+
+	f, err := os.Create(path)
+	if err != nil {
+		log.With("error", err).Errorf("Failed to create coverage report '%s'\n", path)
+		return err
+	}
+	defer f.Close()
+	if err := recorder.WriteLcov(f); err != nil {
+		log.With("error", err).Errorf("Failed to write coverage report '%s'\n", path)
+		return err
+	}
+	return nil
+}
+
+// newTracer creates a tracer for the --trace flags, writing to path or stderr if it's empty. The returned function
+// closes the trace file once the script has finished.
+func newTracer(log *zap.SugaredLogger, format, path, source string) (*trace.Tracer, func(), error) {
+	f, err := trace.ParseFormat(format)
+	if err != nil {
+		log.With("error", err).Error("Invalid --trace-format flag")
+		return nil, nil, err
+	}
+	if path == "" {
+		return trace.New(os.Stderr, f, source), func() {}, nil
+	}
+
+	out, err := os.Create(path)
+	if err != nil {
+		log.With("error", err).Errorf("Failed to create trace '%s'\n", path)
+		return nil, nil, err
+	}
+	return trace.New(out, f, source), func() { out.Close() }, nil
+}
+
+// parseAllowFlag returns the capabilities granted by the --allow flag
+func parseAllowFlag(log *zap.SugaredLogger, allow []string) ([]builtins.Capability, error) {
+	caps, err := builtins.ParseCapabilities(allow)
+	if err != nil {
+		log.With("error", err).Error("Invalid --allow flag")
+	}
+	return caps, err
+}
+
+func newLintCmd(log *zap.SugaredLogger) *cobra.Command {
+	return &cobra.Command{
+		Use:   "lint <file>...",
+		Short: "lint <file>... reports likely mistakes in Lox files, such as unused variables or unreachable code",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			total := 0
+			for _, file := range args {
+				source, err := os.ReadFile(file)
+				if err != nil {
+					log.With("error", err).Errorf("Failed to read file '%s' from disk\n", file)
+					return err
+				}
+				warnings, err := analysis.Lint(string(source), log)
+				if err != nil {
+					log.With("error", err).Errorf("Failed to lint file '%s'\n", file)
+					return err
+				}
+				for _, w := range warnings {
+					fmt.Printf("%s:%s\n", file, w)
+				}
+				total += len(warnings)
+			}
+
+			if total > 0 {
+				return fmt.Errorf("found %d lint warnings", total)
+			}
+			return nil
+		},
+	}
+}
+
+func newLSPCmd(log *zap.SugaredLogger) *cobra.Command {
+	return &cobra.Command{
+		Use:   "lsp",
+		Short: "lsp runs a Language Server Protocol server over stdio, for editor integration",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Logs go to stderr, so they never interfere with the protocol on stdout
+			return lsp.NewServer(os.Stdin, os.Stdout, log).Serve()
+		},
+	}
+}
+
+func newDebugCmd(log *zap.SugaredLogger, allow *[]string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "debug <file>",
+		Short: "debug <file> runs a Lox file in an interactive debugger, with breakpoints and stepping",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			program, err := os.ReadFile(args[0])
+			if err != nil {
+				log.With("error", err).Errorf("Failed to read file '%s' from disk\n", args[0])
+				return err
+			}
+			caps, err := parseAllowFlag(log, *allow)
+			if err != nil {
+				return err
+			}
+			return debugger.New(log, os.Stdin, os.Stdout, interpreter.WithCapabilities(caps...)).Run(string(program))
+		},
+	}
+}
+
+func newTestCmd(log *zap.SugaredLogger, allow *[]string) *cobra.Command {
+	var parallelism int
+	var junitPath string
+	var timeout time.Duration
+	cmd := &cobra.Command{
+		Use:   "test <dir>",
+		Short: "test <dir> runs every Lox file in <dir>, checking their output against '// expect:' comments",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			caps, err := parseAllowFlag(log, *allow)
+			if err != nil {
+				return err
+			}
+			results, err := testrunner.RunDir(args[0], parallelism, timeout, interpreter.WithCapabilities(caps...))
+			if err != nil {
+				log.With("error", err).Errorf("Failed to find tests in '%s'\n", args[0])
+				return err
+			}
+			testrunner.Report(os.Stdout, results)
+
+			if junitPath != "" {
+				f, err := os.Create(junitPath)
+				if err != nil {
+					log.With("error", err).Errorf("Failed to create JUnit report '%s'\n", junitPath)
+					return err
+				}
+				defer f.Close()
+				if err := testrunner.WriteJUnit(f, results); err != nil {
+					log.With("error", err).Errorf("Failed to write JUnit report '%s'\n", junitPath)
+					return err
+				}
+			}
+
+			for _, r := range results {
+				if !r.Passed() {
+					return errors.New("tests failed")
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&parallelism, "parallel", runtime.NumCPU(), "number of test files to run at once")
+	cmd.Flags().StringVar(&junitPath, "junit", "", "write a JUnit XML report to this path")
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "fail any test file still running after this long, or 0 for no limit")
+	return cmd
+}
+
+func newBenchCmd(log *zap.SugaredLogger, allow *[]string) *cobra.Command {
+	var runs, warmup int
+	cmd := &cobra.Command{
+		Use:   "bench <file>",
+		Short: "bench <file> runs a Lox file repeatedly, reporting its mean, p50 and p99 run times and allocations",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			program, err := os.ReadFile(args[0])
+			if err != nil {
+				log.With("error", err).Errorf("Failed to read file '%s' from disk\n", args[0])
+				return err
+			}
+			caps, err := parseAllowFlag(log, *allow)
+			if err != nil {
+				return err
+			}
+
+			stats, err := bench.Run(string(program), runs, warmup, interpreter.WithCapabilities(caps...))
+			if err != nil {
+				log.With("error", err).Errorf("Failed to benchmark file '%s'\n", args[0])
+				return err
+			}
+			bench.Report(os.Stdout, stats)
+			return nil
+		},
+	}
+	cmd.Flags().IntVarP(&runs, "runs", "n", 20, "number of times to run the file")
+	cmd.Flags().IntVar(&warmup, "warmup", 1, "number of unmeasured runs before measuring")
+	return cmd
+}
+
+func newASTCmd(log *zap.SugaredLogger) *cobra.Command {
+	var format string
+	cmd := &cobra.Command{
+		Use:   "ast <file>",
+		Short: "ast <file> prints the parse tree of a Lox file, as S-expressions or JSON",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "sexpr" && format != "json" {
+				log.Errorf("Unknown format '%s', expected 'sexpr' or 'json'\n", format)
+				return fmt.Errorf("unknown format '%s'", format)
+			}
+			source, err := os.ReadFile(args[0])
+			if err != nil {
+				log.With("error", err).Errorf("Failed to read file '%s' from disk\n", args[0])
+				return err
+			}
+			stmts, err := parser.NewParser(log, lexer.NewScanner(string(source), log).ScanTokens()).Parse()
+			if err != nil {
+				log.With("error", err).Errorf("Failed to parse file '%s'\n", args[0])
+				return err
+			}
+
+			if format == "sexpr" {
+				fmt.Print((&parser.ExprPrinter{}).PrintProgram(stmts))
+				return nil
+			}
+			out, err := parser.MarshalJSON(stmts)
+			if err != nil {
+				log.With("error", err).Errorf("Failed to encode the AST of '%s'\n", args[0])
+				return err
+			}
+			fmt.Println(string(out))
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", "sexpr", "output format, either sexpr or json")
+	return cmd
+}
+
+func newTokensCmd(log *zap.SugaredLogger) *cobra.Command {
+	var format string
+	cmd := &cobra.Command{
+		Use:   "tokens <file>",
+		Short: "tokens <file> prints the tokens scanned from a Lox file, as a table or JSON",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "table" && format != "json" {
+				log.Errorf("Unknown format '%s', expected 'table' or 'json'\n", format)
+				return fmt.Errorf("unknown format '%s'", format)
+			}
+			source, err := os.ReadFile(args[0])
+			if err != nil {
+				log.With("error", err).Errorf("Failed to read file '%s' from disk\n", args[0])
+				return err
+			}
+			scanner := lexer.NewScanner(string(source), log)
+			tokens := scanner.ScanTokens()
+
+			if format == "table" {
+				if err := lexer.WriteTokenTable(os.Stdout, tokens); err != nil {
+					log.With("error", err).Errorf("Failed to write the tokens of '%s'\n", args[0])
+					return err
+				}
+			} else {
+				out, err := lexer.MarshalTokensJSON(tokens)
+				if err != nil {
+					log.With("error", err).Errorf("Failed to encode the tokens of '%s'\n", args[0])
+					return err
+				}
+				fmt.Println(string(out))
+			}
+
+			// The scanner has already logged each error, the tokens it did scan are still printed
+			if errs := scanner.Errors(); len(errs) > 0 {
+				return fmt.Errorf("found %d scan errors", len(errs))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", "table", "output format, either table or json")
+	return cmd
+}
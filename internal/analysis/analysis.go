@@ -0,0 +1,248 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/levpaul/glocks/internal/lexer"
+	"github.com/levpaul/glocks/internal/parser"
+	"github.com/levpaul/glocks/internal/resolver"
+	"go.uber.org/zap"
+)
+
+// Rule IDs for each of the checks the Analyzer performs, these are used in warnings and to suppress them
+const (
+	RuleUnusedVariable    = "unused-variable"
+	RuleUnusedParameter   = "unused-parameter"
+	RuleUnreachableCode   = "unreachable-code"
+	RuleShadowedVariable  = "shadowed-variable"
+	RuleSelfAssignment    = "self-assignment"
+	RuleConstantCondition = "constant-condition"
+)
+
+// Warning is a single finding of the Analyzer. Unlike resolver errors, warnings do not stop a program from running.
+type Warning struct {
+	Rule    string
+	Pos     lexer.Position
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s [%s]", w.Pos, w.Message, w.Rule)
+}
+
+// symbol is a single declared name within a scope
+type symbol struct {
+	name string
+	kind string
+	pos  lexer.Position
+	read bool
+}
+
+// scope holds the symbols declared in a single block or function body. The global scope is never
+// checked for unused symbols, as globals may be used by later REPL input.
+type scope struct {
+	symbols map[string]*symbol
+	order   []*symbol
+	global  bool
+}
+
+// Analyzer is a static analysis pass over a resolved AST. It walks scopes in the same way as the
+// resolver.Resolver, but rather than failing on errors, it collects warnings about code which is
+// legal but most likely a mistake.
+type Analyzer struct {
+	// scopes is a stack of scopes, with the zero index being the innermost scope, as in the resolver
+	scopes   []*scope
+	warnings []Warning
+}
+
+// NewAnalyzer returns an Analyzer with only the global scope
+func NewAnalyzer() *Analyzer {
+	return &Analyzer{
+		scopes: []*scope{{symbols: map[string]*symbol{}, global: true}},
+	}
+}
+
+// Lint scans, parses and resolves source, then runs the Analyzer over it. Any hard error from those
+// stages is returned as an error, otherwise the warnings which haven't been suppressed are returned.
+func Lint(source string, log *zap.SugaredLogger) ([]Warning, error) {
+	tokens := lexer.NewScanner(source, log).ScanTokens()
+	stmts, err := parser.NewParser(log, tokens).Parse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse, err='%w'", err)
+	}
+	if err = resolver.NewResolver().ResolveNodes(stmts); err != nil {
+		return nil, fmt.Errorf("static analysis [resolver] FAILURE, err='%w'", err)
+	}
+	warnings, err := NewAnalyzer().Analyze(stmts)
+	if err != nil {
+		return nil, err
+	}
+	return Suppress(source, warnings), nil
+}
+
+// Analyze walks the given statements and returns all warnings found, ordered by position
+func (a *Analyzer) Analyze(stmts []parser.Node) ([]Warning, error) {
+	if err := a.statements(stmts); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(a.warnings, func(i, j int) bool {
+		pi, pj := a.warnings[i].Pos, a.warnings[j].Pos
+		if pi.Line != pj.Line {
+			return pi.Line < pj.Line
+		}
+		return pi.Column < pj.Column
+	})
+	return a.warnings, nil
+}
+
+func (a *Analyzer) warn(rule string, pos lexer.Position, format string, args ...any) {
+	a.warnings = append(a.warnings, Warning{
+		Rule:    rule,
+		Pos:     pos,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (a *Analyzer) analyze(node parser.Node) error {
+	return node.Accept(a)
+}
+
+// statements analyzes a list of statements, warning on the first statement following one which
+// always returns
+func (a *Analyzer) statements(stmts []parser.Node) error {
+	terminated := false
+	for _, stmt := range stmts {
+		if terminated {
+			a.warn(RuleUnreachableCode, stmt.Position(), "unreachable code after return")
+			terminated = false // only report the first unreachable statement of a run
+		} else if terminates(stmt) {
+			terminated = true
+		}
+		if err := a.analyze(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *Analyzer) beginScope() {
+	a.scopes = append([]*scope{{symbols: map[string]*symbol{}}}, a.scopes...)
+}
+
+// endScope pops the innermost scope, warning on any symbols in it which were never read
+func (a *Analyzer) endScope() {
+	s := a.scopes[0]
+	a.scopes = a.scopes[1:]
+	if s.global {
+		return
+	}
+	for _, sym := range s.order {
+		if sym.read {
+			continue
+		}
+		if sym.kind == "parameter" {
+			a.warn(RuleUnusedParameter, sym.pos, "parameter '%s' is never used", sym.name)
+		} else {
+			a.warn(RuleUnusedVariable, sym.pos, "local %s '%s' is declared but never used", sym.kind, sym.name)
+		}
+	}
+}
+
+// declare adds a symbol to the innermost scope, warning if it shadows a symbol in an enclosing scope
+func (a *Analyzer) declare(name, kind string, pos lexer.Position) {
+	for _, s := range a.scopes[1:] {
+		if outer, exists := s.symbols[name]; exists {
+			a.warn(RuleShadowedVariable, pos, "declaration of '%s' shadows %s declared at %s", name, outer.kind, outer.pos)
+			break
+		}
+	}
+	sym := &symbol{name: name, kind: kind, pos: pos}
+	a.scopes[0].symbols[name] = sym
+	a.scopes[0].order = append(a.scopes[0].order, sym)
+}
+
+// markRead marks the innermost symbol with the given name as having been read
+func (a *Analyzer) markRead(name string) {
+	for _, s := range a.scopes {
+		if sym, exists := s.symbols[name]; exists {
+			sym.read = true
+			return
+		}
+	}
+}
+
+func (a *Analyzer) function(f *parser.FunctionDeclaration) error {
+	a.beginScope()
+	for idx, p := range f.Params {
+		if def := f.Default(idx); def != nil {
+			if err := a.analyze(def); err != nil {
+				return err
+			}
+		}
+		a.declare(p.Lexeme, "parameter", p.Position())
+	}
+	if err := a.statements(f.Body); err != nil {
+		return err
+	}
+	a.endScope()
+	return nil
+}
+
+// terminates reports whether a statement always returns from the enclosing function
+func terminates(stmt parser.Node) bool {
+	switch s := stmt.(type) {
+	case *parser.ReturnStmt:
+		return true
+	case *parser.Block:
+		for _, inner := range s.Statements {
+			if terminates(inner) {
+				return true
+			}
+		}
+	case *parser.IfStmt:
+		return s.ElseStatement != nil && terminates(s.Statement) && terminates(s.ElseStatement)
+	}
+	return false
+}
+
+// isConstant reports whether an expression is made up only of literals, and so always evaluates the same
+func isConstant(expr parser.Node) bool {
+	switch e := expr.(type) {
+	case *parser.Literal:
+		return true
+	case *parser.Grouping:
+		return isConstant(e.Expression)
+	case *parser.Unary:
+		return isConstant(e.Right)
+	case *parser.Binary:
+		return isConstant(e.Left) && isConstant(e.Right)
+	case *parser.LogicalConjuction:
+		return isConstant(e.Left) && isConstant(e.Right)
+	}
+	return false
+}
+
+// unwrapGrouping returns the innermost expression of any nested groupings
+func unwrapGrouping(expr parser.Node) parser.Node {
+	for {
+		g, ok := expr.(*parser.Grouping)
+		if !ok {
+			return expr
+		}
+		expr = g.Expression
+	}
+}
+
+// sameReceiver reports whether two instance expressions of get or set expressions plainly refer to the same object
+func sameReceiver(a, b parser.Node) bool {
+	switch av := unwrapGrouping(a).(type) {
+	case *parser.Variable:
+		bv, ok := unwrapGrouping(b).(*parser.Variable)
+		return ok && av.TokenName == bv.TokenName
+	case *parser.ThisExpr:
+		_, ok := unwrapGrouping(b).(*parser.ThisExpr)
+		return ok
+	}
+	return false
+}
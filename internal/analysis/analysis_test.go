@@ -0,0 +1,75 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func lintRules(t *testing.T, program string) []string {
+	warnings, err := Lint(program, zap.S())
+	require.NoError(t, err, "expected no hard errors linting program: `%s`", program)
+	var rules []string
+	for _, w := range warnings {
+		rules = append(rules, w.Rule)
+	}
+	return rules
+}
+
+func TestLintRules(t *testing.T) {
+	cases := map[string][]string{
+		`fun f() { var x = 1; }`:                         {RuleUnusedVariable},
+		`fun f(a, b) { return a; }`:                      {RuleUnusedParameter},
+		`fun f() { return 1; print "never"; }`:           {RuleUnreachableCode},
+		`var x = 1; fun f() { var x = 2; print x; }`:     {RuleShadowedVariable},
+		`var x = 1; x = x;`:                              {RuleSelfAssignment},
+		`class A { m() { this.v = this.v; } }`:           {RuleSelfAssignment},
+		`if (1 < 2) print "always";`:                     {RuleConstantCondition},
+		`while (false) print "never";`:                   {RuleConstantCondition},
+		`while (true) print "forever";`:                  nil,
+		`for (var i = 0; ; i = i + 1) print i;`:          nil,
+		`fun f(a) { if (a) return 1; else return 2; }`:   nil,
+		`fun f(a) { if (a) { return 1; } return 2; }`:    nil,
+		`{ var unused; }`:                                {RuleUnusedVariable},
+		`var global = 1;`:                                nil,
+		`fun outer() { fun inner() {} }`:                 {RuleUnusedVariable},
+		`fun f(n) { fun g() { return n; } return g; }`:   nil,
+		`fun f() { var a = 1; { var a = 2; print a; } }`: {RuleUnusedVariable, RuleShadowedVariable},
+	}
+	for program, expected := range cases {
+		assert.Equal(t, expected, lintRules(t, program), "linting program: `%s`", program)
+	}
+}
+
+func TestLintWarningPosition(t *testing.T) {
+	program := `fun f() {
+  var used = 1;
+  var unused = 2;
+  return used;
+}`
+	warnings, err := Lint(program, zap.S())
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+	assert.Equal(t, "3:7: local variable 'unused' is declared but never used [unused-variable]", warnings[0].String())
+}
+
+func TestLintSuppression(t *testing.T) {
+	program := `fun f(a, b) { // lint:ignore unused-parameter
+  var x; // lint:ignore
+  {
+    // lint:ignore shadowed-variable
+    var x = 1;
+    print x;
+  }
+  return a;
+  print a; // lint:ignore unused-variable
+}`
+	assert.Equal(t, []string{RuleUnreachableCode}, lintRules(t, program))
+}
+
+func TestLintHardError(t *testing.T) {
+	_, err := Lint(`return 1;`, zap.S())
+	assert.ErrorContains(t, err, "detected return statement from global scope")
+}
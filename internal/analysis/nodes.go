@@ -0,0 +1,192 @@
+package analysis
+
+import (
+	"github.com/levpaul/glocks/internal/parser"
+)
+
+func (a *Analyzer) VisitIfStmt(i *parser.IfStmt) error {
+	if isConstant(i.Expression) {
+		a.warn(RuleConstantCondition, i.Position(), "'if' condition is always the same value")
+	}
+	if err := a.analyze(i.Expression); err != nil {
+		return err
+	}
+	if err := a.analyze(i.Statement); err != nil {
+		return err
+	}
+	if i.ElseStatement != nil {
+		return a.analyze(i.ElseStatement)
+	}
+	return nil
+}
+
+func (a *Analyzer) VisitBlock(b *parser.Block) error {
+	a.beginScope()
+	if err := a.statements(b.Statements); err != nil {
+		return err
+	}
+	a.endScope()
+	return nil
+}
+
+func (a *Analyzer) VisitBinary(b *parser.Binary) error {
+	if err := a.analyze(b.Left); err != nil {
+		return err
+	}
+	return a.analyze(b.Right)
+}
+
+func (a *Analyzer) VisitGrouping(g *parser.Grouping) error {
+	return a.analyze(g.Expression)
+}
+
+func (a *Analyzer) VisitLiteral(l *parser.Literal) error {
+	return nil
+}
+
+func (a *Analyzer) VisitUnary(u *parser.Unary) error {
+	return a.analyze(u.Right)
+}
+
+func (a *Analyzer) VisitVariable(v *parser.Variable) error {
+	a.markRead(v.TokenName)
+	return nil
+}
+
+func (a *Analyzer) VisitPrintStmt(p *parser.PrintStmt) error {
+	return a.analyze(p.Arg)
+}
+
+func (a *Analyzer) VisitVarStmt(v *parser.VarStmt) error {
+	if v.Initializer != nil {
+		if err := a.analyze(v.Initializer); err != nil {
+			return err
+		}
+	}
+	a.declare(v.Name, "variable", v.Position())
+	return nil
+}
+
+// VisitAssignment checks for a variable being assigned to itself. Assigning to a variable does not
+// count as reading it.
+func (a *Analyzer) VisitAssignment(v *parser.Assignment) error {
+	if rhs, ok := unwrapGrouping(v.Value).(*parser.Variable); ok && rhs.TokenName == v.TokenName {
+		a.warn(RuleSelfAssignment, v.Position(), "variable '%s' is assigned to itself", v.TokenName)
+	}
+	return a.analyze(v.Value)
+}
+
+func (a *Analyzer) VisitLogicalConjunction(v *parser.LogicalConjuction) error {
+	if err := a.analyze(v.Left); err != nil {
+		return err
+	}
+	return a.analyze(v.Right)
+}
+
+// VisitWhileStmt warns on constant loop conditions, other than a literal 'true' which is the idiomatic
+// infinite loop, and also what a 'for' loop without a condition is desugared to
+func (a *Analyzer) VisitWhileStmt(w *parser.WhileStmt) error {
+	if l, isLit := w.Expression.(*parser.Literal); !(isLit && l.Value == true) && isConstant(w.Expression) {
+		a.warn(RuleConstantCondition, w.Position(), "loop condition is always the same value")
+	}
+	if err := a.analyze(w.Expression); err != nil {
+		return err
+	}
+	return a.analyze(w.Body)
+}
+
+func (a *Analyzer) VisitForInStmt(f *parser.ForInStmt) error {
+	if err := a.analyze(f.Iterable); err != nil {
+		return err
+	}
+	a.beginScope()
+	if err := a.analyze(f.Variable); err != nil {
+		return err
+	}
+	if err := a.analyze(f.Body); err != nil {
+		return err
+	}
+	a.endScope()
+	return nil
+}
+
+func (a *Analyzer) VisitCallExpr(f *parser.CallExpr) error {
+	if err := a.analyze(f.Callee); err != nil {
+		return err
+	}
+	for _, arg := range f.Args {
+		if err := a.analyze(arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *Analyzer) VisitFunctionDeclaration(f *parser.FunctionDeclaration) error {
+	a.declare(f.Name, "function", f.Position())
+	return a.function(f)
+}
+
+func (a *Analyzer) VisitReturnStmt(r *parser.ReturnStmt) error {
+	if r.Expression != nil {
+		return a.analyze(r.Expression)
+	}
+	return nil
+}
+
+func (a *Analyzer) VisitYieldStmt(y *parser.YieldStmt) error {
+	if y.Expression != nil {
+		return a.analyze(y.Expression)
+	}
+	return nil
+}
+
+func (a *Analyzer) VisitClassDeclaration(c *parser.ClassDeclaration) error {
+	if c.SuperClass != nil {
+		if err := a.analyze(c.SuperClass); err != nil {
+			return err
+		}
+	}
+	for _, t := range c.Traits {
+		if err := a.analyze(t); err != nil {
+			return err
+		}
+	}
+	kind := "class"
+	if c.Trait {
+		kind = "trait"
+	}
+	a.declare(c.Name, kind, c.Position())
+	for _, method := range c.Methods {
+		if fd, ok := method.(*parser.FunctionDeclaration); ok {
+			if err := a.function(fd); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (a *Analyzer) VisitGetExpr(g *parser.GetExpr) error {
+	return a.analyze(g.Instance)
+}
+
+// VisitSetExpr checks for a property being assigned to itself, such as `this.x = this.x`
+func (a *Analyzer) VisitSetExpr(s *parser.SetExpr) error {
+	if get, ok := unwrapGrouping(s.Value).(*parser.GetExpr); ok &&
+		get.Name.Lexeme == s.Name.Lexeme && sameReceiver(get.Instance, s.Instance) {
+		a.warn(RuleSelfAssignment, s.Position(), "property '%s' is assigned to itself", s.Name.Lexeme)
+	}
+	if err := a.analyze(s.Instance); err != nil {
+		return err
+	}
+	return a.analyze(s.Value)
+}
+
+func (a *Analyzer) VisitThisExpr(t *parser.ThisExpr) error {
+	return nil
+}
+
+func (a *Analyzer) VisitSuperExpr(s *parser.SuperExpr) error {
+	return nil
+}
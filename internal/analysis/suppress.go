@@ -0,0 +1,55 @@
+package analysis
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ignoreDirective matches comments which suppress warnings, either for every rule with `// lint:ignore` or for
+// specific rules with `// lint:ignore unused-variable, shadowed-variable`
+var ignoreDirective = regexp.MustCompile(`//\s*lint:ignore\b([\w\-, ]*)`)
+
+// Suppress filters out any warnings that are suppressed by an ignore directive in source. A directive at the
+// end of a line applies to that line, while a directive on a line of its own applies to the line following it.
+func Suppress(source string, warnings []Warning) []Warning {
+	// ignored maps line numbers to the rules ignored on that line, where a nil slice means all rules
+	ignored := map[int][]string{}
+	for idx, line := range strings.Split(source, "\n") {
+		match := ignoreDirective.FindStringSubmatchIndex(line)
+		if match == nil {
+			continue
+		}
+
+		lineNum := idx + 1
+		if strings.TrimSpace(line[:match[0]]) == "" {
+			lineNum++
+		}
+
+		var rules []string
+		for _, rule := range strings.Split(line[match[2]:match[3]], ",") {
+			if rule = strings.TrimSpace(rule); rule != "" {
+				rules = append(rules, rule)
+			}
+		}
+		ignored[lineNum] = rules
+	}
+
+	var res []Warning
+	for _, w := range warnings {
+		rules, found := ignored[w.Pos.Line]
+		if found && (rules == nil || contains(rules, w.Rule)) {
+			continue
+		}
+		res = append(res, w)
+	}
+	return res
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
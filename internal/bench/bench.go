@@ -0,0 +1,133 @@
+// Package bench measures how long Lox programs take to run, both for Go benchmarks of each stage of the
+// interpreter and for the glocks bench command.
+package bench
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"path"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/levpaul/glocks/internal/interpreter"
+	"go.uber.org/zap"
+)
+
+//go:embed programs/*.lox
+var programFiles embed.FS
+
+// Program is a representative Lox program used for benchmarking
+type Program struct {
+	Name   string
+	Source string
+}
+
+// Programs returns the representative programs benchmarked by the Go benchmarks, sorted by name
+func Programs() []Program {
+	entries, err := programFiles.ReadDir("programs")
+	if err != nil {
+		panic(fmt.Sprintf("failed to read embedded benchmark programs: %v", err))
+	}
+	programs := make([]Program, 0, len(entries))
+	for _, e := range entries {
+		source, err := programFiles.ReadFile(path.Join("programs", e.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("failed to read embedded benchmark program '%s': %v", e.Name(), err))
+		}
+		programs = append(programs, Program{Name: strings.TrimSuffix(e.Name(), ".lox"), Source: string(source)})
+	}
+	return programs
+}
+
+// Stats summarises the timings and allocations of running a program several times
+type Stats struct {
+	Runs int
+	Mean time.Duration
+	P50  time.Duration
+	P99  time.Duration
+	Min  time.Duration
+	Max  time.Duration
+	// AllocsPerRun and BytesPerRun are the mean number of heap allocations and bytes allocated by each run
+	AllocsPerRun uint64
+	BytesPerRun  uint64
+}
+
+// Run runs a program the given number of times, each in a fresh interpreter configured by opts, after first
+// running it warmup times without measuring. Output of the program is discarded.
+func Run(source string, runs, warmup int, opts ...interpreter.Option) (Stats, error) {
+	if runs < 1 {
+		return Stats{}, fmt.Errorf("expected at least 1 run, got %d", runs)
+	}
+	log := zap.NewNop().Sugar()
+	opts = append([]interpreter.Option{interpreter.WithStdout(io.Discard)}, opts...)
+	runOnce := func() error {
+		return interpreter.New(log, opts...).Run(source)
+	}
+
+	for n := 0; n < warmup; n++ {
+		if err := runOnce(); err != nil {
+			return Stats{}, err
+		}
+	}
+
+	durations := make([]time.Duration, runs)
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	for n := range durations {
+		start := time.Now()
+		if err := runOnce(); err != nil {
+			return Stats{}, err
+		}
+		durations[n] = time.Since(start)
+	}
+	runtime.ReadMemStats(&after)
+
+	stats := summarise(durations)
+	stats.AllocsPerRun = (after.Mallocs - before.Mallocs) / uint64(runs)
+	stats.BytesPerRun = (after.TotalAlloc - before.TotalAlloc) / uint64(runs)
+	return stats, nil
+}
+
+// summarise computes the timing statistics of a set of runs
+func summarise(durations []time.Duration) Stats {
+	sorted := append([]time.Duration{}, durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+	return Stats{
+		Runs: len(sorted),
+		Mean: total / time.Duration(len(sorted)),
+		P50:  percentile(sorted, 50),
+		P99:  percentile(sorted, 99),
+		Min:  sorted[0],
+		Max:  sorted[len(sorted)-1],
+	}
+}
+
+// percentile returns the p-th percentile of sorted durations, using the nearest rank method
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100 // ceil(p/100 * n)
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// Report writes stats in a human readable form
+func Report(w io.Writer, s Stats) {
+	fmt.Fprintf(w, "runs:        %d\n", s.Runs)
+	fmt.Fprintf(w, "mean:        %s\n", s.Mean)
+	fmt.Fprintf(w, "p50:         %s\n", s.P50)
+	fmt.Fprintf(w, "p99:         %s\n", s.P99)
+	fmt.Fprintf(w, "min:         %s\n", s.Min)
+	fmt.Fprintf(w, "max:         %s\n", s.Max)
+	fmt.Fprintf(w, "allocs/run:  %d\n", s.AllocsPerRun)
+	fmt.Fprintf(w, "bytes/run:   %d\n", s.BytesPerRun)
+}
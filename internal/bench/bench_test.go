@@ -0,0 +1,110 @@
+package bench
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/levpaul/glocks/internal/interpreter"
+	"github.com/levpaul/glocks/internal/lexer"
+	"github.com/levpaul/glocks/internal/parser"
+	"github.com/levpaul/glocks/internal/resolver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func BenchmarkScan(b *testing.B) {
+	log := zap.NewNop().Sugar()
+	for _, p := range Programs() {
+		b.Run(p.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				lexer.NewScanner(p.Source, log).ScanTokens()
+			}
+		})
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	log := zap.NewNop().Sugar()
+	for _, p := range Programs() {
+		tokens := lexer.NewScanner(p.Source, log).ScanTokens()
+		b.Run(p.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				if _, err := parser.NewParser(log, tokens).Parse(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkResolve(b *testing.B) {
+	log := zap.NewNop().Sugar()
+	for _, p := range Programs() {
+		stmts, err := parser.NewParser(log, lexer.NewScanner(p.Source, log).ScanTokens()).Parse()
+		require.NoError(b, err)
+		b.Run(p.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				if err := resolver.NewResolver().ResolveNodes(stmts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkRun measures running each program from source, which is dominated by evaluation
+func BenchmarkRun(b *testing.B) {
+	log := zap.NewNop().Sugar()
+	for _, p := range Programs() {
+		b.Run(p.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				if err := interpreter.New(log, interpreter.WithStdout(io.Discard)).Run(p.Source); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestProgramsRun(t *testing.T) {
+	programs := Programs()
+	require.NotEmpty(t, programs)
+	for _, p := range programs {
+		_, err := Run(p.Source, 1, 0)
+		assert.NoError(t, err, "expected benchmark program '%s' to run", p.Name)
+	}
+}
+
+func TestRun(t *testing.T) {
+	stats, err := Run(`var s = ""; for (var i = 0; i < 10; i = i + 1) { s = s + "x"; }`, 5, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 5, stats.Runs)
+	assert.Positive(t, stats.AllocsPerRun)
+	assert.Positive(t, stats.BytesPerRun)
+	assert.LessOrEqual(t, stats.Min, stats.P50)
+	assert.LessOrEqual(t, stats.P50, stats.P99)
+	assert.LessOrEqual(t, stats.P99, stats.Max)
+
+	_, err = Run(`print undefined;`, 5, 0)
+	assert.Error(t, err)
+}
+
+func TestSummarise(t *testing.T) {
+	durations := make([]time.Duration, 100)
+	for idx := range durations {
+		durations[idx] = time.Duration(100-idx) * time.Millisecond
+	}
+	stats := summarise(durations)
+	assert.Equal(t, 100, stats.Runs)
+	assert.Equal(t, 50500*time.Microsecond, stats.Mean)
+	assert.Equal(t, 50*time.Millisecond, stats.P50)
+	assert.Equal(t, 99*time.Millisecond, stats.P99)
+	assert.Equal(t, time.Millisecond, stats.Min)
+	assert.Equal(t, 100*time.Millisecond, stats.Max)
+}
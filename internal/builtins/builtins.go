@@ -6,10 +6,28 @@ import (
 	"time"
 )
 
+// Str converts any value to the string it would be printed as
+type Str struct{}
+
+func (s *Str) Arity() parser.Arity {
+	return parser.ExactArity(1)
+}
+
+func (s *Str) Call(i parser.LoxInterpreter, args []domain.Value) (domain.Value, error) {
+	str, err := i.Stringify(args[0])
+	if err != nil {
+		return nil, err
+	}
+	if err = i.AllocateString(len(str)); err != nil {
+		return nil, err
+	}
+	return str, nil
+}
+
 type Clock struct{}
 
-func (c *Clock) Arity() int {
-	return 0
+func (c *Clock) Arity() parser.Arity {
+	return parser.ExactArity(0)
 }
 func (c *Clock) Call(i parser.LoxInterpreter, args []domain.Value) (domain.Value, error) {
 	return float64(time.Now().Unix()), nil
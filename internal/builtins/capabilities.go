@@ -0,0 +1,101 @@
+package builtins
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/levpaul/glocks/internal/parser"
+)
+
+// Capability names a set of native functions which a host may grant to the scripts it runs
+type Capability string
+
+const (
+	// Time allows reading the clock
+	Time Capability = "time"
+	// FSRead allows reading files
+	FSRead Capability = "fs.read"
+	// FSWrite allows creating and writing files
+	FSWrite Capability = "fs.write"
+	// Env allows reading environment variables
+	Env Capability = "env"
+	// Net allows making network requests
+	Net Capability = "net"
+)
+
+// DefaultCapabilities are granted to interpreters which aren't given capabilities explicitly
+var DefaultCapabilities = []Capability{Time}
+
+// Native is a function implemented in Go which scripts can call when its capability has been granted
+type Native struct {
+	Name string
+	// Capability is the capability which must be granted to call the native, natives without one are always defined
+	Capability Capability
+	Fn         parser.LoxCallable
+}
+
+// Natives is every native function, in the order they are registered
+var Natives = []Native{
+	{Name: "clock", Capability: Time, Fn: &Clock{}},
+	{Name: "readFile", Capability: FSRead, Fn: &ReadFile{}},
+	{Name: "writeFile", Capability: FSWrite, Fn: &WriteFile{}},
+	{Name: "getenv", Capability: Env, Fn: &Getenv{}},
+	{Name: "httpGet", Capability: Net, Fn: &HTTPGet{}},
+	{Name: "str", Fn: &Str{}},
+}
+
+// Capabilities returns every known capability, sorted by name
+func Capabilities() []Capability {
+	seen := map[Capability]bool{}
+	var caps []Capability
+	for _, n := range Natives {
+		if n.Capability != "" && !seen[n.Capability] {
+			seen[n.Capability] = true
+			caps = append(caps, n.Capability)
+		}
+	}
+	sort.Slice(caps, func(i, j int) bool { return caps[i] < caps[j] })
+	return caps
+}
+
+// ParseCapabilities converts capability names, such as those given on the command line, into capabilities.
+// The name "all" grants every capability.
+func ParseCapabilities(names []string) ([]Capability, error) {
+	known := Capabilities()
+	var caps []Capability
+	for _, name := range names {
+		if name == "all" {
+			return known, nil
+		}
+		found := false
+		for _, c := range known {
+			if string(c) == name {
+				caps = append(caps, c)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown capability '%s', expected one of: all, %s", name, joinCapabilities(known))
+		}
+	}
+	return caps, nil
+}
+
+// Lookup returns the native function with the given name, whether or not its capability has been granted
+func Lookup(name string) (Native, bool) {
+	for _, n := range Natives {
+		if n.Name == name {
+			return n, true
+		}
+	}
+	return Native{}, false
+}
+
+func joinCapabilities(caps []Capability) string {
+	names := make([]string, len(caps))
+	for idx, c := range caps {
+		names[idx] = string(c)
+	}
+	return strings.Join(names, ", ")
+}
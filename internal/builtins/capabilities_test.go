@@ -0,0 +1,21 @@
+package builtins
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCapabilities(t *testing.T) {
+	caps, err := ParseCapabilities([]string{"time", "fs.read"})
+	require.NoError(t, err)
+	assert.Equal(t, []Capability{Time, FSRead}, caps)
+
+	caps, err = ParseCapabilities([]string{"all"})
+	require.NoError(t, err)
+	assert.Equal(t, []Capability{Env, FSRead, FSWrite, Net, Time}, caps)
+
+	_, err = ParseCapabilities([]string{"time", "fs"})
+	assert.EqualError(t, err, "unknown capability 'fs', expected one of: all, env, fs.read, fs.write, net, time")
+}
@@ -0,0 +1,150 @@
+package builtins
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/levpaul/glocks/internal/domain"
+	"github.com/levpaul/glocks/internal/parser"
+)
+
+// httpTimeout is the longest an httpGet request may take, including reading its response, when its run isn't
+// cancelled first
+const httpTimeout = 30 * time.Second
+
+var httpClient = &http.Client{Timeout: httpTimeout}
+
+// ReadFile returns the contents of the file at the given path as a string
+type ReadFile struct{}
+
+func (r *ReadFile) Arity() parser.Arity {
+	return parser.ExactArity(1)
+}
+
+func (r *ReadFile) Call(i parser.LoxInterpreter, args []domain.Value) (domain.Value, error) {
+	path, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("readFile expects a string path, but got '%v'", args[0])
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("readFile failed: %w", err)
+	}
+	defer f.Close()
+	// Files such as FIFOs and those in /proc report a size of 0, so the size is only used to fail early
+	size := int64(-1)
+	if info, err := f.Stat(); err == nil && info.Mode().IsRegular() {
+		size = info.Size()
+	}
+	contents, err := readString(i, f, size)
+	if err != nil {
+		return nil, fmt.Errorf("readFile failed: %w", err)
+	}
+	return contents, nil
+}
+
+// WriteFile writes a string to the file at the given path, replacing any existing contents
+type WriteFile struct{}
+
+func (w *WriteFile) Arity() parser.Arity {
+	return parser.ExactArity(2)
+}
+
+func (w *WriteFile) Call(i parser.LoxInterpreter, args []domain.Value) (domain.Value, error) {
+	path, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("writeFile expects a string path, but got '%v'", args[0])
+	}
+	contents, ok := args[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("writeFile expects string contents, but got '%v'", args[1])
+	}
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		return nil, fmt.Errorf("writeFile failed: %w", err)
+	}
+	return nil, nil
+}
+
+// Getenv returns the value of an environment variable, or the optional fallback when it isn't set, which defaults
+// to nil
+type Getenv struct{}
+
+func (g *Getenv) Arity() parser.Arity {
+	return parser.Arity{Min: 1, Max: 2}
+}
+
+func (g *Getenv) Call(i parser.LoxInterpreter, args []domain.Value) (domain.Value, error) {
+	name, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("getenv expects a string name, but got '%v'", args[0])
+	}
+	if val, exists := os.LookupEnv(name); exists {
+		if err := i.AllocateString(len(val)); err != nil {
+			return nil, err
+		}
+		return val, nil
+	}
+	if len(args) > 1 {
+		return args[1], nil
+	}
+	return nil, nil
+}
+
+// HTTPGet makes a GET request to a URL, returning the body of the response as a string. The request is abandoned
+// when the run is cancelled, or after httpTimeout.
+type HTTPGet struct{}
+
+func (h *HTTPGet) Arity() parser.Arity {
+	return parser.ExactArity(1)
+}
+
+func (h *HTTPGet) Call(i parser.LoxInterpreter, args []domain.Value) (domain.Value, error) {
+	url, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("httpGet expects a string url, but got '%v'", args[0])
+	}
+	req, err := http.NewRequestWithContext(i.Context(), http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("httpGet failed: %w", err)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("httpGet failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("httpGet failed: %s returned status %s", url, resp.Status)
+	}
+	body, err := readString(i, resp.Body, resp.ContentLength)
+	if err != nil {
+		return nil, fmt.Errorf("httpGet failed: %w", err)
+	}
+	return body, nil
+}
+
+// readString reads all of r as a string, counting it towards the memory limit. A size over the memory available,
+// where a negative size is unknown, fails before reading, and reading stops as soon as more than the memory
+// available has arrived, so that huge or endless readers fail rather than being loaded into memory.
+func readString(i parser.LoxInterpreter, r io.Reader, size int64) (string, error) {
+	available, limited := i.MemoryAvailable()
+	if limited {
+		if size > available {
+			return "", fmt.Errorf("%w: %d bytes to read with %d bytes available", domain.ErrMemoryLimitExceeded, size, available)
+		}
+		r = io.LimitReader(r, available+1)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	if limited && int64(len(data)) > available {
+		return "", fmt.Errorf("%w: read more than the %d bytes available", domain.ErrMemoryLimitExceeded, available)
+	}
+	if err = i.AllocateString(len(data)); err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
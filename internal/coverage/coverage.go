@@ -0,0 +1,243 @@
+// Package coverage records which statements and branches of a Lox script run, and reports them in lcov format.
+package coverage
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/levpaul/glocks/internal/lexer"
+	"github.com/levpaul/glocks/internal/parser"
+	"go.uber.org/zap"
+)
+
+// nodeKey identifies a node by its position and type, which is the same for each parse of a script. Both are
+// needed as a for loop's desugared statements share the position of the 'for' keyword.
+type nodeKey struct {
+	pos  lexer.Position
+	kind reflect.Type
+}
+
+func keyOf(node parser.Node) nodeKey {
+	return nodeKey{pos: node.Position(), kind: reflect.TypeOf(node)}
+}
+
+// branchPoint is a node which can go one of two ways, such as an if statement
+type branchPoint struct {
+	pos lexer.Position
+	// taken and notTaken count each way the branch went, as described by interpreter.BranchHook
+	taken    int
+	notTaken int
+}
+
+// Recorder is an interpreter.StatementHook and interpreter.BranchHook which records the coverage of a single
+// script. It parses the script itself to find every statement and branch, including those which never run.
+type Recorder struct {
+	filename   string
+	statements map[nodeKey]*int
+	branches   map[nodeKey]*branchPoint
+}
+
+// New creates a Recorder for a script, failing if the script can't be parsed
+func New(filename, source string, log *zap.SugaredLogger) (*Recorder, error) {
+	stmts, err := parser.NewParser(log, lexer.NewScanner(source, log).ScanTokens()).Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Recorder{
+		filename:   filename,
+		statements: map[nodeKey]*int{},
+		branches:   map[nodeKey]*branchPoint{},
+	}
+	r.addStatements(stmts)
+	parser.InspectAll(stmts, func(node parser.Node) bool {
+		switch node.(type) {
+		case *parser.IfStmt, *parser.WhileStmt, *parser.ForInStmt, *parser.LogicalConjuction:
+			r.branches[keyOf(node)] = &branchPoint{pos: node.Position()}
+		}
+		return true
+	})
+	return r, nil
+}
+
+// addStatements adds every statement which the interpreter may execute, within the given statements
+func (r *Recorder) addStatements(stmts []parser.Node) {
+	for _, stmt := range stmts {
+		r.addStatement(stmt)
+	}
+}
+
+func (r *Recorder) addStatement(stmt parser.Node) {
+	if stmt == nil {
+		return
+	}
+	// Blocks aren't counted themselves, just the statements within them
+	if b, isBlock := stmt.(*parser.Block); isBlock {
+		r.addStatements(b.Statements)
+		return
+	}
+
+	r.statements[keyOf(stmt)] = new(int)
+	switch s := stmt.(type) {
+	case *parser.IfStmt:
+		r.addStatement(s.Statement)
+		r.addStatement(s.ElseStatement)
+	case *parser.WhileStmt:
+		r.addStatement(s.Body)
+	case *parser.ForInStmt:
+		r.addStatement(s.Body)
+	case *parser.FunctionDeclaration:
+		r.addStatements(s.Body)
+	case *parser.ClassDeclaration:
+		for _, m := range s.Methods {
+			if method, ok := m.(*parser.FunctionDeclaration); ok {
+				r.addStatements(method.Body)
+			}
+		}
+	}
+}
+
+// BeforeStatement implements interpreter.StatementHook, counting each time a statement runs
+func (r *Recorder) BeforeStatement(stmt parser.Node) error {
+	if stmt == nil {
+		return nil
+	}
+	if hits, exists := r.statements[keyOf(stmt)]; exists {
+		*hits++
+	}
+	return nil
+}
+
+// Branch implements interpreter.BranchHook, counting each way a branch goes
+func (r *Recorder) Branch(node parser.Node, taken bool) {
+	b, exists := r.branches[keyOf(node)]
+	if !exists {
+		return
+	}
+	if taken {
+		b.taken++
+	} else {
+		b.notTaken++
+	}
+}
+
+// Summary is the coverage of a single script
+type Summary struct {
+	Filename          string
+	Statements        int
+	StatementsCovered int
+	// Branches counts both ways of each branch point separately
+	Branches        int
+	BranchesCovered int
+	// UncoveredLines are the lines with statements that never ran
+	UncoveredLines []int
+}
+
+// Summary totals the statements and branches that were covered
+func (r *Recorder) Summary() Summary {
+	s := Summary{Filename: r.filename, Statements: len(r.statements), Branches: 2 * len(r.branches)}
+	for _, hits := range r.statements {
+		if *hits > 0 {
+			s.StatementsCovered++
+		}
+	}
+	for _, b := range r.branches {
+		if b.taken > 0 {
+			s.BranchesCovered++
+		}
+		if b.notTaken > 0 {
+			s.BranchesCovered++
+		}
+	}
+	for line, hits := range r.lineHits() {
+		if hits == 0 {
+			s.UncoveredLines = append(s.UncoveredLines, line)
+		}
+	}
+	sort.Ints(s.UncoveredLines)
+	return s
+}
+
+// String formats a summary as a single line for the terminal
+func (s Summary) String() string {
+	str := fmt.Sprintf("%s: %s of statements (%d/%d), %s of branches (%d/%d)", s.Filename,
+		percent(s.StatementsCovered, s.Statements), s.StatementsCovered, s.Statements,
+		percent(s.BranchesCovered, s.Branches), s.BranchesCovered, s.Branches)
+	if len(s.UncoveredLines) > 0 {
+		lines := make([]string, len(s.UncoveredLines))
+		for idx, l := range s.UncoveredLines {
+			lines[idx] = fmt.Sprint(l)
+		}
+		str += ", lines not run: " + strings.Join(lines, ", ")
+	}
+	return str
+}
+
+func percent(n, total int) string {
+	if total == 0 {
+		return "100.0%"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(n)/float64(total))
+}
+
+// lineHits returns the number of times each line with a statement ran, taking the most run statement of a line
+func (r *Recorder) lineHits() map[int]int {
+	lines := map[int]int{}
+	for key, hits := range r.statements {
+		if current, exists := lines[key.pos.Line]; !exists || *hits > current {
+			lines[key.pos.Line] = *hits
+		}
+	}
+	return lines
+}
+
+// WriteLcov writes the coverage of the script as an lcov tracefile record, with line data for statements and
+// branch data for each way of every branch point
+func (r *Recorder) WriteLcov(w io.Writer) error {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "TN:\nSF:%s\n", r.filename)
+
+	branches := make([]*branchPoint, 0, len(r.branches))
+	for _, bp := range r.branches {
+		branches = append(branches, bp)
+	}
+	sort.Slice(branches, func(i, j int) bool {
+		if branches[i].pos.Line != branches[j].pos.Line {
+			return branches[i].pos.Line < branches[j].pos.Line
+		}
+		return branches[i].pos.Column < branches[j].pos.Column
+	})
+	summary := r.Summary()
+	for idx, bp := range branches {
+		for branch, hits := range []int{bp.taken, bp.notTaken} {
+			// Branches of a branch point that was never reached are reported as '-' rather than zero
+			taken := "-"
+			if bp.taken+bp.notTaken > 0 {
+				taken = fmt.Sprint(hits)
+			}
+			fmt.Fprintf(b, "BRDA:%d,%d,%d,%s\n", bp.pos.Line, idx, branch, taken)
+		}
+	}
+	fmt.Fprintf(b, "BRF:%d\nBRH:%d\n", summary.Branches, summary.BranchesCovered)
+
+	lineHits := r.lineHits()
+	lines := make([]int, 0, len(lineHits))
+	for line := range lineHits {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	covered := 0
+	for _, line := range lines {
+		fmt.Fprintf(b, "DA:%d,%d\n", line, lineHits[line])
+		if lineHits[line] > 0 {
+			covered++
+		}
+	}
+	fmt.Fprintf(b, "LF:%d\nLH:%d\nend_of_record\n", len(lines), covered)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,87 @@
+package coverage
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/levpaul/glocks/internal/interpreter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const program = `fun classify(n) {
+  if (n > 10) {
+    return "big";
+  }
+  return "small";
+}
+var neverCalled = false;
+if (neverCalled and classify(1) == "big") {
+  print "unreachable";
+}
+for (var i = 0; i < 3; i = i + 1) {
+  print classify(i);
+}
+`
+
+func recordCoverage(t *testing.T, source string) *Recorder {
+	log := zap.NewNop().Sugar()
+	r, err := New("test.lox", source, log)
+	require.NoError(t, err)
+	i := interpreter.New(log, interpreter.WithStatementHook(r), interpreter.WithBranchHook(r), interpreter.WithStdout(io.Discard))
+	require.NoError(t, i.Run(source))
+	return r
+}
+
+func TestSummary(t *testing.T) {
+	s := recordCoverage(t, program).Summary()
+
+	// fun, if, return "big", return "small", var, if, print "unreachable", var i, while, print, increment
+	assert.Equal(t, 11, s.Statements)
+	assert.Equal(t, 9, s.StatementsCovered)
+	// Branch points are both ifs, the and, and the for loop's condition
+	assert.Equal(t, 8, s.Branches)
+	// The if within classify and the and are never true, neither is the if using it
+	assert.Equal(t, 5, s.BranchesCovered)
+	assert.Equal(t, []int{3, 9}, s.UncoveredLines)
+	assert.Equal(t, "test.lox: 81.8% of statements (9/11), 62.5% of branches (5/8), lines not run: 3, 9", s.String())
+}
+
+func TestWriteLcov(t *testing.T) {
+	out := &strings.Builder{}
+	require.NoError(t, recordCoverage(t, program).WriteLcov(out))
+
+	assert.Equal(t, `TN:
+SF:test.lox
+BRDA:2,0,0,0
+BRDA:2,0,1,3
+BRDA:8,1,0,0
+BRDA:8,1,1,1
+BRDA:8,2,0,0
+BRDA:8,2,1,1
+BRDA:11,3,0,3
+BRDA:11,3,1,1
+BRF:8
+BRH:5
+DA:1,1
+DA:2,3
+DA:3,0
+DA:5,3
+DA:7,1
+DA:8,1
+DA:9,0
+DA:11,3
+DA:12,3
+LF:9
+LH:7
+end_of_record
+`, out.String())
+}
+
+func TestUnreachedBranchesAreMarked(t *testing.T) {
+	out := &strings.Builder{}
+	require.NoError(t, recordCoverage(t, "fun f(x) { if (x) print x; }").WriteLcov(out))
+	assert.Contains(t, out.String(), "BRDA:1,0,0,-\nBRDA:1,0,1,-\n")
+}
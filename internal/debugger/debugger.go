@@ -0,0 +1,299 @@
+package debugger
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/levpaul/glocks/internal/domain"
+	"github.com/levpaul/glocks/internal/environment"
+	"github.com/levpaul/glocks/internal/interpreter"
+	"github.com/levpaul/glocks/internal/parser"
+	"go.uber.org/zap"
+)
+
+// ErrQuit is returned when the user quits the debugger before the program has finished
+var ErrQuit = errors.New("quit debugger")
+
+type stepMode int
+
+const (
+	modeContinue stepMode = iota
+	modeStepIn
+	modeStepOver
+	modeStepOut
+)
+
+const helpText = `Commands:
+  c, continue        run until the next breakpoint
+  s, step            step to the next statement, entering function calls
+  n, next            step to the next statement in the current function, or the one it returns to
+  o, out             run until the current function returns
+  b, break LINE      set a breakpoint on LINE
+  clear LINE         remove the breakpoint on LINE
+  bt, backtrace      show the call stack
+  up, down           select the frame above or below the selected one
+  l, locals          show local variables of the selected frame
+  globals            show global variables
+  p, print EXPR      evaluate EXPR in the selected frame
+  list               show the source around the current line
+  q, quit            stop the program and exit`
+
+// Debugger is an interactive, command line debugger for Lox programs. It hooks into the interpreter before
+// each statement is executed, pausing when a breakpoint is hit or a step completes and reading commands
+// until the user resumes execution.
+type Debugger struct {
+	log         *zap.SugaredLogger
+	in          *bufio.Scanner
+	out         io.Writer
+	interpreter *interpreter.Interpreter
+	source      []string
+	breakpoints map[int]bool
+
+	mode stepMode
+	// depth is the call depth when the last step command was issued
+	depth int
+	// selected is the index of the frame, from the innermost, that locals and print commands use
+	selected int
+	// lastStmt and lastLine are the last statement executed, used to avoid pausing repeatedly on one line
+	lastStmt parser.Node
+	lastLine int
+	// paused is whether commands are being read, during which any Lox code they run, such as functions called by
+	// print commands, runs without pausing
+	paused bool
+}
+
+// New returns a Debugger which reads commands from in and writes its output to out. Options configure the
+// interpreter the program is debugged in.
+func New(log *zap.SugaredLogger, in io.Reader, out io.Writer, opts ...interpreter.Option) *Debugger {
+	d := &Debugger{
+		log:         log,
+		in:          bufio.NewScanner(in),
+		out:         out,
+		breakpoints: map[int]bool{},
+	}
+	d.interpreter = interpreter.New(log, append([]interpreter.Option{interpreter.WithStatementHook(d)}, opts...)...)
+	return d
+}
+
+// Run debugs a Lox program, pausing before its first statement. Quitting the debugger is not treated as an error.
+func (d *Debugger) Run(program string) error {
+	d.source = strings.Split(program, "\n")
+	d.mode = modeStepIn
+	fmt.Fprintln(d.out, "glocks debugger - type 'help' for a list of commands")
+
+	err := d.interpreter.Run(program)
+	if errors.Is(err, ErrQuit) {
+		return nil
+	}
+	if err == nil {
+		fmt.Fprintln(d.out, "Program finished")
+	}
+	return err
+}
+
+// BeforeStatement implements interpreter.StatementHook, pausing when a step completes or a breakpoint is hit
+func (d *Debugger) BeforeStatement(stmt parser.Node) error {
+	if d.paused {
+		return nil
+	}
+	if _, isBlock := stmt.(*parser.Block); isBlock {
+		return nil // pause on the statements within the block instead
+	}
+
+	line := stmt.Position().Line
+	depth := d.interpreter.CallDepth()
+	pause := false
+	switch d.mode {
+	case modeStepIn:
+		pause = true
+	case modeStepOver:
+		pause = depth <= d.depth
+	case modeStepOut:
+		pause = depth < d.depth
+	}
+	// Only hit a breakpoint once for a line with several statements, but every time a loop re-runs the line
+	if d.breakpoints[line] && (line != d.lastLine || stmt == d.lastStmt) {
+		pause = true
+	}
+	d.lastStmt, d.lastLine = stmt, line
+
+	if !pause {
+		return nil
+	}
+	return d.prompt(line, depth)
+}
+
+// prompt reads and runs commands until one resumes execution
+func (d *Debugger) prompt(line, depth int) error {
+	d.paused = true
+	defer func() { d.paused = false }()
+	d.selected = 0
+	d.printLine(line, "=>")
+	for {
+		fmt.Fprint(d.out, "(debug) ")
+		if !d.in.Scan() {
+			return ErrQuit
+		}
+		cmd, arg, _ := strings.Cut(strings.TrimSpace(d.in.Text()), " ")
+		arg = strings.TrimSpace(arg)
+
+		switch cmd {
+		case "":
+		case "c", "continue":
+			d.mode = modeContinue
+			return nil
+		case "s", "step":
+			d.mode = modeStepIn
+			return nil
+		case "n", "next":
+			d.mode, d.depth = modeStepOver, depth
+			return nil
+		case "o", "out":
+			d.mode, d.depth = modeStepOut, depth
+			return nil
+		case "b", "break":
+			if n, ok := d.parseLine(arg); ok {
+				d.breakpoints[n] = true
+				fmt.Fprintf(d.out, "Breakpoint set on line %d\n", n)
+			}
+		case "clear":
+			if n, ok := d.parseLine(arg); ok {
+				delete(d.breakpoints, n)
+				fmt.Fprintf(d.out, "Breakpoint cleared on line %d\n", n)
+			}
+		case "bt", "backtrace":
+			for idx, f := range d.interpreter.CallStack() {
+				marker := " "
+				if idx == d.selected {
+					marker = "*"
+				}
+				fmt.Fprintf(d.out, "%s #%d %s at line %d\n", marker, idx, f.Name, f.Pos.Line)
+			}
+		case "up", "down":
+			d.selectFrame(cmd)
+		case "l", "locals":
+			d.printLocals(d.selectedFrame().Env)
+		case "globals":
+			d.printScope(d.globalEnv())
+		case "p", "print":
+			d.evaluate(arg)
+		case "list":
+			d.list(d.selectedFrame().Pos.Line)
+		case "q", "quit":
+			return ErrQuit
+		case "h", "help":
+			fmt.Fprintln(d.out, helpText)
+		default:
+			fmt.Fprintf(d.out, "Unknown command '%s', type 'help' for a list of commands\n", cmd)
+		}
+	}
+}
+
+func (d *Debugger) parseLine(arg string) (int, bool) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 || n > len(d.source) {
+		fmt.Fprintf(d.out, "Expected a line number between 1 and %d, got '%s'\n", len(d.source), arg)
+		return 0, false
+	}
+	return n, true
+}
+
+func (d *Debugger) selectedFrame() interpreter.StackFrame {
+	return d.interpreter.CallStack()[d.selected]
+}
+
+func (d *Debugger) selectFrame(direction string) {
+	stack := d.interpreter.CallStack()
+	if direction == "up" && d.selected < len(stack)-1 {
+		d.selected++
+	} else if direction == "down" && d.selected > 0 {
+		d.selected--
+	}
+	f := stack[d.selected]
+	fmt.Fprintf(d.out, "#%d %s at line %d\n", d.selected, f.Name, f.Pos.Line)
+}
+
+// globalEnv returns the outermost environment, which holds the globals
+func (d *Debugger) globalEnv() *environment.Environment {
+	env := d.interpreter.GetEnvironment()
+	for env.Enclosing != nil {
+		env = env.Enclosing
+	}
+	return env
+}
+
+// printLocals prints every scope from env outwards, stopping before the globals
+func (d *Debugger) printLocals(env *environment.Environment) {
+	if env.Enclosing == nil {
+		fmt.Fprintln(d.out, "No locals at the top level, use 'globals' instead")
+		return
+	}
+	for depth := 0; env.Enclosing != nil; depth, env = depth+1, env.Enclosing {
+		fmt.Fprintf(d.out, "scope %d:\n", depth)
+		d.printScope(env)
+	}
+}
+
+func (d *Debugger) printScope(env *environment.Environment) {
+	vars := env.Variables()
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(d.out, "  %s = %s\n", name, d.stringify(vars[name]))
+	}
+}
+
+func (d *Debugger) evaluate(expr string) {
+	if expr == "" {
+		fmt.Fprintln(d.out, "Expected an expression to print")
+		return
+	}
+	if !strings.HasSuffix(expr, ";") {
+		expr += ";"
+	}
+	val, err := d.interpreter.EvaluateIn(expr, d.selectedFrame().Env)
+	if err != nil {
+		fmt.Fprintf(d.out, "Error: %v\n", err)
+		return
+	}
+	fmt.Fprintln(d.out, d.stringify(val))
+}
+
+// stringify returns a value as Lox prints it, or the error of stringifying it, such as one of a failing toString()
+func (d *Debugger) stringify(v domain.Value) string {
+	str, err := d.interpreter.Stringify(v)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	return str
+}
+
+func (d *Debugger) list(line int) {
+	for n := line - 3; n <= line+3; n++ {
+		if n < 1 || n > len(d.source) {
+			continue
+		}
+		marker := "  "
+		if n == line {
+			marker = "=>"
+		} else if d.breakpoints[n] {
+			marker = " *"
+		}
+		d.printLine(n, marker)
+	}
+}
+
+func (d *Debugger) printLine(line int, marker string) {
+	if line < 1 || line > len(d.source) {
+		return
+	}
+	fmt.Fprintf(d.out, "%s %4d: %s\n", marker, line, d.source[line-1])
+}
@@ -0,0 +1,92 @@
+package debugger
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const program = `fun add(a, b) {
+  var sum = a + b;
+  return sum;
+}
+var x = add(1, 2);
+var y = x;`
+
+func runSession(t *testing.T, commands ...string) string {
+	var out strings.Builder
+	d := New(zap.NewNop().Sugar(), strings.NewReader(strings.Join(commands, "\n")), &out)
+	require.NoError(t, d.Run(program))
+	return out.String()
+}
+
+func TestBreakpointAndInspection(t *testing.T) {
+	out := runSession(t, "break 3", "continue", "bt", "locals", "print a * 10", "up", "print sum", "next", "print x", "quit")
+
+	assert.Contains(t, out, "=>    1: fun add(a, b) {")
+	assert.Contains(t, out, "Breakpoint set on line 3")
+	assert.Contains(t, out, "=>    3:   return sum;")
+	assert.Contains(t, out, "* #0 add at line 3\n  #1 <script> at line 5\n")
+	assert.Contains(t, out, "scope 0:\n  a = 1\n  b = 2\n  sum = 3\n")
+	assert.Contains(t, out, "(debug) 10\n")
+	assert.Contains(t, out, "#1 <script> at line 5\n(debug) Error:")
+	assert.Contains(t, out, "=>    6: var y = x;")
+	assert.Contains(t, out, "(debug) 3\n")
+	assert.NotContains(t, out, "Program finished")
+}
+
+func TestStepping(t *testing.T) {
+	out := runSession(t, "next", "step", "step", "out", "continue")
+	lines := []string{}
+	for _, line := range strings.Split(out, "\n") {
+		if idx := strings.Index(line, "=>"); idx >= 0 {
+			lines = append(lines, strings.TrimSpace(line[idx+2:]))
+		}
+	}
+	assert.Equal(t, []string{
+		"1: fun add(a, b) {",
+		"5: var x = add(1, 2);",
+		"2:   var sum = a + b;",
+		"3:   return sum;",
+		"6: var y = x;",
+	}, lines)
+	assert.Contains(t, out, "Program finished")
+}
+
+func TestPrintCallsFunctionsWithoutPausing(t *testing.T) {
+	var out strings.Builder
+	d := New(zap.NewNop().Sugar(), strings.NewReader(strings.Join([]string{
+		"step", "step", "print add(5, 6)", "print nil", "print P()", "continue"}, "\n")), &out)
+	require.NoError(t, d.Run(`class P { toString() { return "a P"; } }
+fun add(a, b) {
+  return a + b;
+}
+var p = P();`))
+
+	assert.Contains(t, out.String(), "(debug) 11\n(debug) nil\n(debug) a P\n")
+	// The only pauses are before each top level statement, and none within add()
+	assert.Equal(t, 3, strings.Count(out.String(), "=>"))
+	assert.Contains(t, out.String(), "Program finished")
+}
+
+func TestPrintSuperAndThis(t *testing.T) {
+	var out strings.Builder
+	d := New(zap.NewNop().Sugar(), strings.NewReader(strings.Join([]string{
+		"break 6", "continue", "print super.name()", "print this.name()", "print super.missing", "continue"}, "\n")), &out)
+	require.NoError(t, d.Run(`class A { name() { return "A"; } }
+class B < A {
+  name() { return "B"; }
+  show() {
+    var unused = 1;
+    return super.name();
+  }
+}
+print B().show();`))
+
+	assert.Contains(t, out.String(), "(debug) A\n(debug) B\n(debug) Error:")
+	assert.Contains(t, out.String(), "Undefined property 'missing'")
+	assert.Contains(t, out.String(), "Program finished")
+}
@@ -1,3 +1,9 @@
 package domain
 
+import "errors"
+
 type Value any
+
+// ErrMemoryLimitExceeded is returned when a program's memory use grows beyond its limit, whether in the
+// interpreter or in the native functions it calls
+var ErrMemoryLimitExceeded = errors.New("memory limit exceeded")
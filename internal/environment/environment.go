@@ -7,27 +7,57 @@ import (
 	"github.com/levpaul/glocks/internal/domain"
 )
 
-// Environment is a recursive data structure that holds a map of variable names to values
-// and a pointer to the enclosing environment. This allows for nested scopes and
-// variable shadowing. The Environment is used to store variables and their values
-// during execution.
+// Environment is a recursive data structure that holds the variables of a scope and a pointer to the
+// enclosing environment. This allows for nested scopes and variable shadowing. The Environment is used
+// to store variables and their values during execution.
+//
+// Local variables are stored in Slots, at the index the resolver assigned them, so they can be accessed
+// without looking up their name. Globals are stored by name in Values, as the names of globals may only be
+// known at runtime, such as for native functions or lines entered into the REPL.
 type Environment struct {
 	Enclosing *Environment
 	Values    map[string]domain.Value
+	Slots     []Slot
+}
+
+// Slot holds a local variable, which keeps its name for tools such as a debugger
+type Slot struct {
+	Name  string
+	Value domain.Value
 }
 
 // NewEnvironment creates a new Environment with the given enclosing environment.
 func NewEnvironment(enclosing *Environment) *Environment {
 	return &Environment{
 		Enclosing: enclosing,
-		Values:    map[string]domain.Value{},
 	}
 }
 
+// NewLocalEnvironment creates a new Environment with the given enclosing environment, with room for size local
+// variables
+func NewLocalEnvironment(enclosing *Environment, size int) *Environment {
+	return &Environment{
+		Enclosing: enclosing,
+		Slots:     make([]Slot, 0, size),
+	}
+}
+
+// Define defines a variable by name
 func (e *Environment) Define(name string, v domain.Value) {
+	if e.Values == nil {
+		e.Values = map[string]domain.Value{}
+	}
 	e.Values[name] = v
 }
 
+// DefineAt defines a variable in the given slot
+func (e *Environment) DefineAt(slot int, name string, v domain.Value) {
+	for len(e.Slots) <= slot {
+		e.Slots = append(e.Slots, Slot{})
+	}
+	e.Slots[slot] = Slot{Name: name, Value: v}
+}
+
 func (e *Environment) ancestor(distance int) (*Environment, error) {
 	currEnv := e
 	for i := 0; i < distance; i++ {
@@ -39,18 +69,22 @@ func (e *Environment) ancestor(distance int) (*Environment, error) {
 	return currEnv, nil
 }
 
-func (e *Environment) GetAt(distance int, name string) (domain.Value, error) {
+// GetAt retrieves the value of the variable in a slot of the environment distance scopes away
+func (e *Environment) GetAt(distance, slot int) (domain.Value, error) {
 	targetEnv, err := e.ancestor(distance)
 	if err != nil {
 		return nil, err
 	}
-	return targetEnv.Get(name)
+	if slot >= len(targetEnv.Slots) {
+		return nil, fmt.Errorf("attempted to get variable in slot %d but it has not been defined", slot)
+	}
+	return targetEnv.Slots[slot].Value, nil
 }
 
-// Get retrieves the value of a variable from the environment. If the variable is not found
-// in the current environment, it will search the enclosing environments recursively.
+// Get retrieves the value of a variable by name. If the variable is not found in the current
+// environment, it will search the enclosing environments recursively.
 func (e *Environment) Get(name string) (domain.Value, error) {
-	if val, found := e.Values[name]; found {
+	if val, found := e.Lookup(name); found {
 		return val, nil
 	}
 
@@ -61,17 +95,57 @@ func (e *Environment) Get(name string) (domain.Value, error) {
 	return nil, fmt.Errorf("attempted to get variable '%s' but does not exist", name)
 }
 
-func (e *Environment) SetAt(distance int, name string, v domain.Value) error {
+// Lookup finds a variable by name within this environment only, not searching its enclosing environments
+func (e *Environment) Lookup(name string) (domain.Value, bool) {
+	if slot, found := e.SlotOf(name); found {
+		return e.Slots[slot].Value, true
+	}
+	val, found := e.Values[name]
+	return val, found
+}
+
+// SlotOf returns the slot holding the variable with the given name in this environment
+func (e *Environment) SlotOf(name string) (int, bool) {
+	for slot, s := range e.Slots {
+		if s.Name == name {
+			return slot, true
+		}
+	}
+	return 0, false
+}
+
+// Variables returns every variable defined in this environment by name, whether it is stored in a slot or not
+func (e *Environment) Variables() map[string]domain.Value {
+	vars := make(map[string]domain.Value, len(e.Values)+len(e.Slots))
+	for name, v := range e.Values {
+		vars[name] = v
+	}
+	for _, s := range e.Slots {
+		vars[s.Name] = s.Value
+	}
+	return vars
+}
+
+// SetAt sets the value of the variable in a slot of the environment distance scopes away
+func (e *Environment) SetAt(distance, slot int, v domain.Value) error {
 	targetEnv, err := e.ancestor(distance)
 	if err != nil {
 		return err
 	}
-	return targetEnv.Set(name, v)
+	if slot >= len(targetEnv.Slots) {
+		return fmt.Errorf("attempted to set variable in slot %d but it has not been defined", slot)
+	}
+	targetEnv.Slots[slot].Value = v
+	return nil
 }
 
-// Set sets the value of a variable in the environment. If the variable is not found
-// in the current environment, it will search and set in the enclosing environments recursively.
+// Set sets the value of a variable by name. If the variable is not found in the current environment,
+// it will search and set in the enclosing environments recursively.
 func (e *Environment) Set(name string, v domain.Value) error {
+	if slot, found := e.SlotOf(name); found {
+		e.Slots[slot].Value = v
+		return nil
+	}
 	if _, found := e.Values[name]; !found {
 		if e.Enclosing == nil {
 			return fmt.Errorf("attempted to set variable '%s' but does not exist", name)
@@ -87,6 +161,7 @@ func (e *Environment) Clone() Environment {
 	newEnv := Environment{
 		Enclosing: e.Enclosing,
 		Values:    map[string]domain.Value{},
+		Slots:     append([]Slot{}, e.Slots...),
 	}
 
 	for k, v := range e.Values {
@@ -0,0 +1,166 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/levpaul/glocks/internal/domain"
+	"github.com/levpaul/glocks/internal/environment"
+	"github.com/levpaul/glocks/internal/lexer"
+	"github.com/levpaul/glocks/internal/parser"
+)
+
+// missingArgument stands in for the arguments a call leaves out before a named argument, so that their
+// parameters take their default values
+type missingArgument struct{}
+
+func (missingArgument) String() string {
+	return "<default>"
+}
+
+var missingArg domain.Value = missingArgument{}
+
+// evaluateArguments evaluates the arguments of a call, matching any named arguments to the parameters of the callee
+// and checking it accepts them
+func (i *Interpreter) evaluateArguments(f *parser.CallExpr, callee parser.LoxCallable) ([]domain.Value, error) {
+	var args []domain.Value
+	for _, a := range f.Args {
+		evaluatedArg, argErr := i.Evaluate(a)
+		if argErr != nil {
+			return nil, argErr
+		}
+		args = append(args, evaluatedArg)
+	}
+
+	positional := len(args)
+	for idx, name := range f.ArgNames {
+		if name != nil {
+			positional = idx
+			break
+		}
+	}
+	arity := callee.Arity()
+	if positional == len(args) {
+		if !arity.Accepts(len(args)) {
+			return nil, fmt.Errorf("Expected %s args to be passed to func, but received %d.", arity, len(args))
+		}
+		return args, nil
+	}
+	// Too few positional arguments may be made up for by named ones, which matchNamedArguments checks
+	if arity.Max != parser.Variadic && positional > arity.Max {
+		return nil, fmt.Errorf("Expected %s args to be passed to func, but received %d.", arity, positional)
+	}
+	return i.matchNamedArguments(callee, args[:positional], f.ArgNames[positional:], args[positional:])
+}
+
+// matchNamedArguments returns the arguments of a call in the order of the callee's parameters, placing each named
+// argument after the positional arguments in the slot of the parameter of its name. Parameters which are named by
+// neither are left for their defaults, and must have one.
+func (i *Interpreter) matchNamedArguments(callee parser.LoxCallable, positional []domain.Value, names []*lexer.Token, named []domain.Value) ([]domain.Value, error) {
+	decl := parameterDeclaration(callee)
+	if decl == nil {
+		return nil, fmt.Errorf("'%s' doesn't take named arguments", callableName(callee))
+	}
+	params := decl.Params
+	if decl.Rest {
+		params = params[:len(params)-1]
+	}
+
+	args := make([]domain.Value, len(params))
+	if len(positional) > len(params) {
+		args = make([]domain.Value, len(positional))
+	}
+	copy(args, positional)
+	for idx := len(positional); idx < len(params); idx++ {
+		args[idx] = missingArg
+	}
+
+	for idx, name := range names {
+		slot := -1
+		for p := range params {
+			if params[p].Lexeme == name.Lexeme {
+				slot = p
+				break
+			}
+		}
+		switch {
+		case slot < 0 && decl.Rest && decl.Params[len(params)].Lexeme == name.Lexeme:
+			return nil, fmt.Errorf("rest parameter '%s' of '%s' can't be given by name", name.Lexeme, callableName(callee))
+		case slot < 0:
+			return nil, fmt.Errorf("'%s' has no parameter named '%s'", callableName(callee), name.Lexeme)
+		case slot < len(positional):
+			return nil, fmt.Errorf("argument '%s' of '%s' is given more than once", name.Lexeme, callableName(callee))
+		}
+		args[slot] = named[idx]
+	}
+
+	for idx, p := range params {
+		if args[idx] == missingArg && decl.Default(idx) == nil {
+			return nil, fmt.Errorf("missing argument '%s' of '%s'", p.Lexeme, callableName(callee))
+		}
+	}
+	// Trailing parameters left for their defaults are left out, as in a call with fewer arguments
+	for len(args) > 0 && args[len(args)-1] == missingArg {
+		args = args[:len(args)-1]
+	}
+	return args, nil
+}
+
+// parameterDeclaration returns the declaration of the parameters of a callable, which for classes are those of
+// their initializer. It is nil for natives, whose parameters have no names.
+func parameterDeclaration(callee parser.LoxCallable) *parser.FunctionDeclaration {
+	switch c := callee.(type) {
+	case LoxFunction:
+		return c.declaration
+	case *LoxClass:
+		if initializer, err := c.findMethod("init"); err == nil {
+			return initializer.declaration
+		}
+	}
+	return nil
+}
+
+// bindArguments defines the parameters of a call of fn in its environment env. Parameters which args leaves out,
+// or passes as missingArg, take their default values, which are evaluated in env so that they can refer to the
+// parameters before them. A rest parameter is defined as a list of the remaining arguments.
+func (i *Interpreter) bindArguments(fn LoxFunction, env *environment.Environment, args []domain.Value) error {
+	params := fn.declaration.Params
+	if fn.declaration.Rest {
+		params = params[:len(params)-1]
+	}
+
+	for idx, p := range params {
+		if idx < len(args) && args[idx] != missingArg {
+			env.DefineAt(idx, p.Lexeme, args[idx])
+			continue
+		}
+		var value domain.Value
+		if def := fn.declaration.Default(idx); def != nil {
+			var err error
+			if value, err = i.evaluateIn(def, env); err != nil {
+				return err
+			}
+		}
+		env.DefineAt(idx, p.Lexeme, value)
+	}
+
+	if fn.declaration.Rest {
+		var rest []domain.Value
+		if len(args) > len(params) {
+			rest = append(rest, args[len(params):]...)
+		}
+		list, err := i.newList(rest)
+		if err != nil {
+			return err
+		}
+		env.DefineAt(len(params), fn.declaration.Params[len(params)].Lexeme, list)
+	}
+	return nil
+}
+
+// evaluateIn evaluates an expression within env, rather than the current environment
+func (i *Interpreter) evaluateIn(expr parser.Node, env *environment.Environment) (domain.Value, error) {
+	previous := i.env
+	i.env = env
+	defer func() { i.env = previous }()
+	return i.Evaluate(expr)
+}
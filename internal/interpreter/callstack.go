@@ -0,0 +1,125 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/levpaul/glocks/internal/builtins"
+	"github.com/levpaul/glocks/internal/domain"
+	"github.com/levpaul/glocks/internal/environment"
+	"github.com/levpaul/glocks/internal/lexer"
+	"github.com/levpaul/glocks/internal/parser"
+)
+
+// frame is a single call on the interpreter's call stack
+type frame struct {
+	name string
+	// pos is the position of the statement this frame is currently executing
+	pos lexer.Position
+	// callerEnv is the environment the caller was executing in when it made this call
+	callerEnv *environment.Environment
+}
+
+// StackFrame is a snapshot of a single frame of the call stack, for use by tools such as a debugger
+type StackFrame struct {
+	// Name is the name of the function called, or "<script>" for the top level of the program
+	Name string
+	// Pos is the position of the statement currently executing in the frame
+	Pos lexer.Position
+	// Env is the innermost environment of the frame, which includes any block scopes within the function
+	Env *environment.Environment
+}
+
+// pushFrame starts a call to callee, notifying any call hooks
+func (i *Interpreter) pushFrame(callee parser.LoxCallable, args []domain.Value) error {
+	if len(i.frames) > i.maxCallDepth {
+		return ErrStackOverflow
+	}
+	name := callableName(callee)
+	i.frames = append(i.frames, &frame{name: name, callerEnv: i.env})
+	for _, h := range i.callHooks {
+		h.EnterCall(name, callableDeclaration(callee), args)
+	}
+	return nil
+}
+
+// replaceFrame starts a tail call to callee in the innermost frame, in place of the function which made it.
+// Call hooks are notified of the call starting as usual.
+func (i *Interpreter) replaceFrame(callee parser.LoxCallable, args []domain.Value) {
+	name := callableName(callee)
+	f := i.frames[len(i.frames)-1]
+	f.name = name
+	f.pos = lexer.Position{}
+	for _, h := range i.callHooks {
+		h.EnterCall(name, callableDeclaration(callee), args)
+	}
+}
+
+// popFrame finishes the innermost call, notifying any call hooks of its result
+func (i *Interpreter) popFrame(result domain.Value, err error) {
+	i.frames = i.frames[:len(i.frames)-1]
+	i.exitCallHooks(result, err)
+}
+
+func (i *Interpreter) exitCallHooks(result domain.Value, err error) {
+	for _, h := range i.callHooks {
+		h.ExitCall(result, err)
+	}
+}
+
+// tailCall is returned by a return statement which returns the result of calling a Lox function, to unwind the
+// function making the call so the call can be made in its place. See Interpreter.call.
+type tailCall struct {
+	callee LoxFunction
+	args   []domain.Value
+}
+
+func (t tailCall) Error() string {
+	return fmt.Sprintf("tail call of '%s' was not made", callableName(t.callee))
+}
+
+// CallDepth returns the number of Lox calls currently in progress, where zero is the top level of the script
+func (i *Interpreter) CallDepth() int {
+	return len(i.frames) - 1
+}
+
+// CallStack returns a snapshot of the call stack, starting with the innermost frame
+func (i *Interpreter) CallStack() []StackFrame {
+	stack := make([]StackFrame, 0, len(i.frames))
+	env := i.env
+	for idx := len(i.frames) - 1; idx >= 0; idx-- {
+		f := i.frames[idx]
+		stack = append(stack, StackFrame{Name: f.name, Pos: f.pos, Env: env})
+		env = f.callerEnv
+	}
+	return stack
+}
+
+// callableName returns the name a callable is shown with in the call stack, with methods qualified by the name
+// of their class
+func callableName(c parser.LoxCallable) string {
+	switch callee := c.(type) {
+	case LoxFunction:
+		if callee.className != "" {
+			return callee.className + "." + callee.declaration.Name
+		}
+		return callee.declaration.Name
+	case *LoxClass:
+		return callee.Name
+	case *native:
+		return callee.name
+	}
+	for _, n := range builtins.Natives {
+		if n.Fn == c {
+			return n.Name
+		}
+	}
+	return "<native fn>"
+}
+
+// callableDeclaration returns where a callable was declared, which is unknown for classes and natives
+func callableDeclaration(c parser.LoxCallable) lexer.Position {
+	if f, isFunction := c.(LoxFunction); isFunction {
+		return f.declaration.Position()
+	}
+	return lexer.Position{}
+}
@@ -0,0 +1,112 @@
+package interpreter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/levpaul/glocks/internal/builtins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestDefaultCapabilities(t *testing.T) {
+	testSimpleProgramWorksWithOutput(t, `print clock() > 0;`, "true")
+
+	_, err := testSimpleProgram(`readFile("/etc/hostname");`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "native function 'readFile' requires the 'fs.read' capability, which has not been granted")
+}
+
+func TestGrantedCapabilities(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "greeting.txt")
+	program := `writeFile("` + path + `", "hello"); print readFile("` + path + `");`
+
+	out := &strings.Builder{}
+	i := New(zap.NewNop().Sugar(), WithStdout(out), WithCapabilities(builtins.FSRead, builtins.FSWrite))
+	require.NoError(t, i.Run(program))
+	assert.Equal(t, "hello\n", out.String())
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(contents))
+}
+
+func TestNoCapabilities(t *testing.T) {
+	i := New(zap.NewNop().Sugar(), WithCapabilities())
+	err := i.Run(`clock();`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "requires the 'time' capability")
+
+	// Scripts may still define their own functions with the names of natives
+	require.NoError(t, i.Run(`fun clock() { return 0; } clock();`))
+}
+
+func TestHTTPGetIsCancelledWithRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	i := New(zap.NewNop().Sugar(), WithCapabilities(builtins.Net))
+	start := time.Now()
+	err := i.RunContext(ctx, `httpGet("`+server.URL+`");`)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestNativeResultsCountTowardsMemoryLimit(t *testing.T) {
+	large := strings.Repeat("x", 256*1024)
+	path := filepath.Join(t.TempDir(), "large.txt")
+	require.NoError(t, os.WriteFile(path, []byte(large), 0o644))
+	t.Setenv("GLOCKS_LARGE", large)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/endless":
+			// Flushing before finishing sends the response without a Content-Length
+			for r.Context().Err() == nil {
+				if _, err := w.Write([]byte(large)); err != nil {
+					return
+				}
+				w.(http.Flusher).Flush()
+			}
+		case "/declared":
+			w.Header().Set("Content-Length", "1000000000")
+		default:
+			_, _ = w.Write([]byte(large))
+		}
+	}))
+	defer server.Close()
+
+	programs := []string{
+		`var s = readFile("` + path + `");`,
+		`var s = readFile("/dev/zero");`,
+		`var s = httpGet("` + server.URL + `");`,
+		`var s = httpGet("` + server.URL + `/endless");`,
+		`var s = httpGet("` + server.URL + `/declared");`,
+		`var s = getenv("GLOCKS_LARGE");`,
+		`var s = str(list("` + large[:64*1024] + `", "` + large[:64*1024] + `"));`,
+	}
+	for _, program := range programs {
+		i := New(zap.NewNop().Sugar(), WithMemoryLimit(128*1024),
+			WithCapabilities(builtins.FSRead, builtins.Net, builtins.Env))
+		require.ErrorIs(t, i.Run(program), ErrMemoryLimitExceeded, program)
+	}
+
+	// Results within the limit are read in full
+	out := &strings.Builder{}
+	i := New(zap.NewNop().Sugar(), WithStdout(out), WithMemoryLimit(1<<20), WithCapabilities(builtins.FSRead, builtins.Net))
+	require.NoError(t, i.Run(`print readFile("`+path+`") == httpGet("`+server.URL+`");`))
+	assert.Equal(t, "true\n", out.String())
+}
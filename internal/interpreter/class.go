@@ -2,24 +2,32 @@ package interpreter
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/levpaul/glocks/internal/domain"
 	"github.com/levpaul/glocks/internal/parser"
 )
 
 type LoxClass struct {
-	Name       string
-	Methods    map[string]LoxFunction
+	Name    string
+	Methods map[string]LoxFunction
+	// StaticMethods are the class methods called on the class itself, bound with the class as 'this'
+	StaticMethods map[string]LoxFunction
+	// Setters are the methods run when their property of an instance is assigned, which may share their name
+	// with a getter in Methods
+	Setters    map[string]LoxFunction
 	SuperClass domain.Value
+	// Traits are the traits the class was declared with
+	Traits []*LoxTrait
 }
 
-func (l LoxClass) Call(i parser.LoxInterpreter, args []domain.Value) (domain.Value, error) {
-	instance := LoxInstance{
+func (l *LoxClass) Call(i parser.LoxInterpreter, args []domain.Value) (domain.Value, error) {
+	instance := &LoxInstance{
 		klass:  l,
 		fields: map[string]domain.Value{},
 	}
-	initializer, exists := l.Methods["init"]
-	if exists {
+	initializer, err := l.findMethod("init")
+	if err == nil {
 		if _, err := initializer.Bind(instance).Call(i, args); err != nil {
 			return nil, err
 		}
@@ -28,29 +36,89 @@ func (l LoxClass) Call(i parser.LoxInterpreter, args []domain.Value) (domain.Val
 	return instance, nil
 }
 
-func (l LoxClass) String() string {
+func (l *LoxClass) String() string {
 	return fmt.Sprintf("<class %s>", l.Name)
 }
 
-func (l LoxClass) Arity() int {
-	initializer, exists := l.Methods["init"]
-	if exists {
+func (l *LoxClass) Arity() parser.Arity {
+	initializer, err := l.findMethod("init")
+	if err == nil {
 		return initializer.Arity()
 	}
-	return 0
+	return parser.ExactArity(0)
+}
+
+// LoxTrait is a set of methods to be included in classes, and other traits, declared with it. Its methods keep
+// the closure of the trait, and so never have a superclass.
+type LoxTrait struct {
+	Name          string
+	Methods       map[string]LoxFunction
+	StaticMethods map[string]LoxFunction
+	Setters       map[string]LoxFunction
+	// Traits are the traits the trait was declared with
+	Traits []*LoxTrait
+}
+
+// includes reports whether the trait is, or was declared with, another trait
+func (l *LoxTrait) includes(trait *LoxTrait) bool {
+	if l == trait {
+		return true
+	}
+	for _, t := range l.Traits {
+		if t.includes(trait) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *LoxTrait) String() string {
+	return fmt.Sprintf("<trait %s>", l.Name)
+}
+
+// includeTraits adds the methods of traits which aren't already declared in own, the methods of the class or trait
+// named class. Methods of each kind are included separately, with methodsOf returning those of a trait. It fails
+// when two traits have different methods of the same name, unless own declares the method itself.
+func includeTraits(class string, traits []*LoxTrait, own map[string]LoxFunction, methodsOf func(*LoxTrait) map[string]LoxFunction) error {
+	origins := map[string]string{}
+	for _, t := range traits {
+		methods := methodsOf(t)
+		names := make([]string, 0, len(methods))
+		for name := range methods {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			method := methods[name]
+			existing, exists := own[name]
+			origin, included := origins[name]
+			switch {
+			case !exists:
+				own[name] = method
+				origins[name] = t.Name
+			case !included:
+				// Declared by the class itself, which overrides the trait
+			case existing.declaration != method.declaration:
+				return fmt.Errorf("'%s' includes conflicting methods '%s' from traits '%s' and '%s', and must declare its own",
+					class, name, origin, t.Name)
+			}
+		}
+	}
+	return nil
 }
 
 type LoxInstance struct {
 	// functions []LoxFunction
-	klass  LoxClass
+	klass  *LoxClass
 	fields map[string]domain.Value
 }
 
-func (l LoxInstance) String() string {
+func (l *LoxInstance) String() string {
 	return l.klass.Name + " instance"
 }
 
-func (l LoxInstance) Get(name string) (domain.Value, error) {
+func (l *LoxInstance) Get(name string) (domain.Value, error) {
 	if val, exists := l.fields[name]; exists {
 		return val, nil
 	}
@@ -63,13 +131,39 @@ func (l LoxInstance) Get(name string) (domain.Value, error) {
 	return method.Bind(l), nil
 }
 
-func (l LoxClass) findMethod(name string) (LoxFunction, error) {
+// Get returns the class method with the given name, which may be inherited, bound to the class
+func (l *LoxClass) Get(name string) (domain.Value, error) {
+	method, err := l.findStaticMethod(name)
+	if err != nil {
+		return nil, err
+	}
+	return method.Bind(l), nil
+}
+
+// findStaticMethod finds a class method of the class or its superclasses
+func (l *LoxClass) findStaticMethod(name string) (LoxFunction, error) {
+	if method, exists := l.StaticMethods[name]; exists {
+		return method, nil
+	}
+
+	if l.SuperClass != nil {
+		superclass, ok := l.SuperClass.(*LoxClass)
+		if !ok {
+			return LoxFunction{}, fmt.Errorf("superclass must be a class, but got '%T'", l.SuperClass)
+		}
+		return superclass.findStaticMethod(name)
+	}
+
+	return LoxFunction{}, fmt.Errorf("Undefined class method '%s' on class '%s'", name, l.Name)
+}
+
+func (l *LoxClass) findMethod(name string) (LoxFunction, error) {
 	if method, exists := l.Methods[name]; exists {
 		return method, nil
 	}
 
 	if l.SuperClass != nil {
-		superclass, ok := l.SuperClass.(LoxClass)
+		superclass, ok := l.SuperClass.(*LoxClass)
 		if !ok {
 			return LoxFunction{}, fmt.Errorf("superclass must be a class, but got '%T'", l.SuperClass)
 		}
@@ -79,6 +173,51 @@ func (l LoxClass) findMethod(name string) (LoxFunction, error) {
 	return LoxFunction{}, fmt.Errorf("Undefined property '%s' on instance of class '%s'", name, l.Name)
 }
 
-func (l LoxInstance) Set(name string, value domain.Value) {
+// isSubclassOf reports whether the class is another class, or inherits from it
+func (l *LoxClass) isSubclassOf(class *LoxClass) bool {
+	for c := l; c != nil; c, _ = c.SuperClass.(*LoxClass) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// includes reports whether the class or one of its superclasses was declared with a trait
+func (l *LoxClass) includes(trait *LoxTrait) bool {
+	for c := l; c != nil; c, _ = c.SuperClass.(*LoxClass) {
+		for _, t := range c.Traits {
+			if t.includes(trait) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// instanceOf reports whether a value is an instance of a class or its subclasses, or of a class including a trait
+func instanceOf(v, classOrTrait domain.Value) (bool, error) {
+	instance, isInstance := v.(*LoxInstance)
+	switch right := classOrTrait.(type) {
+	case *LoxClass:
+		return isInstance && instance.klass.isSubclassOf(right), nil
+	case *LoxTrait:
+		return isInstance && instance.klass.includes(right), nil
+	}
+	return false, fmt.Errorf("the right operand of 'instanceof' must be a class or trait, but got '%v'", classOrTrait)
+}
+
+// findSetter finds a setter of the class or its superclasses, reporting false when there is none
+func (l *LoxClass) findSetter(name string) (LoxFunction, bool) {
+	if setter, exists := l.Setters[name]; exists {
+		return setter, true
+	}
+	if superclass, ok := l.SuperClass.(*LoxClass); ok {
+		return superclass.findSetter(name)
+	}
+	return LoxFunction{}, false
+}
+
+func (l *LoxInstance) Set(name string, value domain.Value) {
 	l.fields[name] = value
 }
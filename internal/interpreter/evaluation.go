@@ -21,36 +21,42 @@ func (e EarlyReturn) Error() string {
 }
 
 func (i *Interpreter) VisitSuperExpr(s *parser.SuperExpr) error {
-	distance, err := i.r.GetLocal(s)
-	if err != nil {
-		return err
+	local, isLocal := i.r.GetLocal(s)
+	if !isLocal {
+		return fmt.Errorf("could not find 'super' for method '%s'", s.Method.Lexeme)
 	}
 
-	superClass, err := i.env.GetAt(distance, "super")
+	superClass, err := i.env.GetAt(local.Depth, local.Slot)
 	if err != nil {
 		return err
 	}
-	superKlass, ok := superClass.(LoxClass)
+	superKlass, ok := superClass.(*LoxClass)
 	if !ok {
 		return fmt.Errorf("superclass must be a class, but got '%v'", superClass)
 	}
 
-	object, err := i.env.GetAt(distance-1, "this")
+	// 'this' is always the only variable of the scope within the one holding 'super'
+	object, err := i.env.GetAt(local.Depth-1, thisSlot)
 	if err != nil {
 		return err
 	}
-	instance, ok := object.(LoxInstance)
-	if !ok {
+
+	var method LoxFunction
+	switch object.(type) {
+	case *LoxInstance:
+		method, err = superKlass.findMethod(s.Method.Lexeme)
+	case *LoxClass:
+		// Within a class method, super refers to the class methods of the superclass
+		method, err = superKlass.findStaticMethod(s.Method.Lexeme)
+	default:
 		return fmt.Errorf("object must be an instance of a class, but got '%v'", object)
 	}
-
-	method, err := superKlass.findMethod(s.Method.Lexeme)
 	if err != nil {
 		return err
 	}
 
-	i.evalRes = method.Bind(instance)
-	return nil
+	i.evalRes, err = i.getProperty(method.Bind(object))
+	return err
 }
 
 func (i *Interpreter) VisitThisExpr(t *parser.ThisExpr) error {
@@ -63,38 +69,72 @@ func (i *Interpreter) VisitThisExpr(t *parser.ThisExpr) error {
 }
 
 func (i *Interpreter) VisitClassDeclaration(c *parser.ClassDeclaration) error {
-	klass := LoxClass{
+	klass := &LoxClass{
 		Name: c.Name,
 	}
 
-	var superClass LoxClass
+	var superClass *LoxClass
 	if c.SuperClass != nil {
 		scEvalRes, err := i.Evaluate(c.SuperClass)
 		if err != nil {
 			return err
 		}
-		sc, ok := scEvalRes.(LoxClass)
+		sc, ok := scEvalRes.(*LoxClass)
 		if !ok {
 			return fmt.Errorf("superclass must be a class, but got '%v'", scEvalRes)
 		}
 		superClass = sc
 	}
 
+	traits := make([]*LoxTrait, len(c.Traits))
+	klass.Traits = traits
+	for idx, t := range c.Traits {
+		traitEvalRes, err := i.Evaluate(t)
+		if err != nil {
+			return err
+		}
+		trait, ok := traitEvalRes.(*LoxTrait)
+		if !ok {
+			return fmt.Errorf("can only include traits with 'with', but '%s' is '%v'", t.TokenName, traitEvalRes)
+		}
+		traits[idx] = trait
+	}
+
+	if err := i.allocate(classSize + int64(len(c.Methods))*(bindingSize+functionSize)); err != nil {
+		return err
+	}
+
 	if c.SuperClass != nil {
-		i.env = environment.NewEnvironment(i.env)
-		i.env.Define("super", superClass)
+		if err := i.allocate(environmentSize + bindingSize); err != nil {
+			return err
+		}
+		i.env = environment.NewLocalEnvironment(i.env, 1)
+		i.env.DefineAt(0, "super", superClass)
 	}
 
 	methods := map[string]LoxFunction{}
+	staticMethods := map[string]LoxFunction{}
+	setters := map[string]LoxFunction{}
 	for _, methodRaw := range c.Methods {
 		method, ok := methodRaw.(*parser.FunctionDeclaration)
 		if !ok {
 			return fmt.Errorf("expected function declaration, but got '%v'", methodRaw)
 		}
-		methods[method.Name] = LoxFunction{
+		fn := LoxFunction{
 			declaration:   method,
 			closure:       i.env,
-			isInitializer: method.Name == "init",
+			isInitializer: method.Name == "init" && !method.Static && !method.Setter,
+			scopeSize:     i.r.ScopeSize(method),
+			className:     c.Name,
+			isGenerator:   i.r.IsGenerator(method),
+		}
+		switch {
+		case method.Static:
+			staticMethods[method.Name] = fn
+		case method.Setter:
+			setters[method.Name] = fn
+		default:
+			methods[method.Name] = fn
 		}
 	}
 
@@ -102,14 +142,58 @@ func (i *Interpreter) VisitClassDeclaration(c *parser.ClassDeclaration) error {
 		i.env = i.env.Enclosing // release the scope for super
 	}
 
+	// The methods a class declares itself take precedence over those of its traits, which in turn take precedence
+	// over those it inherits
+	if err := includeTraits(c.Name, traits, methods, func(t *LoxTrait) map[string]LoxFunction { return t.Methods }); err != nil {
+		return err
+	}
+	if err := includeTraits(c.Name, traits, staticMethods, func(t *LoxTrait) map[string]LoxFunction { return t.StaticMethods }); err != nil {
+		return err
+	}
+	if err := includeTraits(c.Name, traits, setters, func(t *LoxTrait) map[string]LoxFunction { return t.Setters }); err != nil {
+		return err
+	}
+
+	if c.Trait {
+		i.define(c, c.Name, &LoxTrait{
+			Name:          c.Name,
+			Traits:        traits,
+			Methods:       methods,
+			StaticMethods: staticMethods,
+			Setters:       setters,
+		})
+		return nil
+	}
+
 	klass.Methods = methods
-	klass.SuperClass = superClass
-	i.env.Define(c.Name, klass)
+	klass.StaticMethods = staticMethods
+	klass.Setters = setters
+	if c.SuperClass != nil {
+		klass.SuperClass = superClass
+	}
+	i.define(c, c.Name, klass)
 
 	return nil
 }
 
 func (i *Interpreter) VisitReturnStmt(r *parser.ReturnStmt) error {
+	// Calls of Lox functions in tail position are left for the caller to make in place of this function, rather
+	// than nesting within it
+	if call, isTailCall := i.r.TailCall(r); isTailCall {
+		callee, args, err := i.evaluateCall(call)
+		if err != nil {
+			return err
+		}
+		if fn, isFunction := callee.(LoxFunction); isFunction {
+			return tailCall{callee: fn, args: args}
+		}
+		result, err := i.call(callee, args)
+		if err != nil {
+			return err
+		}
+		return EarlyReturn{result: result}
+	}
+
 	var err error
 	earlyReturn := EarlyReturn{}
 	// return here to last func call
@@ -123,10 +207,15 @@ func (i *Interpreter) VisitReturnStmt(r *parser.ReturnStmt) error {
 }
 
 func (i *Interpreter) VisitFunctionDeclaration(f *parser.FunctionDeclaration) error {
-	i.env.Define(f.Name, LoxFunction{
+	if err := i.allocate(bindingSize + functionSize); err != nil {
+		return err
+	}
+	i.define(f, f.Name, LoxFunction{
 		declaration:   f,
 		closure:       i.env,
 		isInitializer: false,
+		scopeSize:     i.r.ScopeSize(f),
+		isGenerator:   i.r.IsGenerator(f),
 	})
 	i.evalRes = nil
 	return nil
@@ -142,41 +231,106 @@ func (i *Interpreter) VisitGetExpr(g *parser.GetExpr) error {
 		return fmt.Errorf("Attempted to get property '%s' from a nil instance", g.Name.Lexeme)
 	}
 
-	loxInstance, ok := evalResult.(LoxInstance)
-	if !ok {
+	switch object := evalResult.(type) {
+	case *LoxInstance:
+		i.evalRes, err = object.Get(g.Name.Lexeme)
+	case *LoxClass:
+		i.evalRes, err = object.Get(g.Name.Lexeme)
+	case *LoxList:
+		i.evalRes, err = object.Get(g.Name.Lexeme)
+	case *LoxGenerator:
+		i.evalRes, err = object.Get(g.Name.Lexeme)
+	default:
 		return fmt.Errorf("Properties can only be called on Class instances. Not on '%v'", evalResult)
 	}
-
-	i.evalRes, err = loxInstance.Get(g.Name.Lexeme)
+	if err != nil {
+		return err
+	}
+	if _, isMethod := i.evalRes.(LoxFunction); isMethod {
+		// Methods are bound to the instance with an environment holding 'this'
+		if err = i.allocate(environmentSize + bindingSize + functionSize); err != nil {
+			return err
+		}
+	}
+	i.evalRes, err = i.getProperty(i.evalRes)
 	return err
 }
 
+// getProperty returns the value of a property which has been read, calling it when it's a getter
+func (i *Interpreter) getProperty(v domain.Value) (domain.Value, error) {
+	if fn, isFunction := v.(LoxFunction); isFunction && fn.isGetter() {
+		return i.call(fn, nil)
+	}
+	return v, nil
+}
+
 func (i *Interpreter) VisitCallExpr(f *parser.CallExpr) error {
-	callee, err := i.Evaluate(f.Callee)
+	callee, args, err := i.evaluateCall(f)
 	if err != nil {
 		return err
 	}
+	i.evalRes, err = i.call(callee, args)
+	return err
+}
 
-	var args []domain.Value
-	for _, a := range f.Args {
-		evaluatedArg, argErr := i.Evaluate(a)
-		if argErr != nil {
-			return argErr
-		}
-		args = append(args, evaluatedArg)
+// evaluateCall evaluates the callee and arguments of a call, checking the callee can be called with them
+func (i *Interpreter) evaluateCall(f *parser.CallExpr) (parser.LoxCallable, []domain.Value, error) {
+	callee, err := i.Evaluate(f.Callee)
+	if err != nil {
+		return nil, nil, err
 	}
-
 	loxFunction, ok := callee.(parser.LoxCallable)
 	if !ok {
-		return fmt.Errorf("Expected %v to be of type Callable!", callee)
+		return nil, nil, fmt.Errorf("Expected %v to be of type Callable!", callee)
 	}
 
-	if len(args) != loxFunction.Arity() {
-		return fmt.Errorf("Expected %d args to be passed to func, but only received %d.", loxFunction.Arity(), len(args))
+	args, err := i.evaluateArguments(f, loxFunction)
+	if err != nil {
+		return nil, nil, err
 	}
+	return loxFunction, args, nil
+}
 
-	i.evalRes, err = loxFunction.Call(i, args)
-	return err
+// call calls a callable in a new frame of the call stack. When the callee makes a tail call, the call replaces
+// the callee in the same frame, so chains of tail calls run in constant Go stack and call depth.
+func (i *Interpreter) call(callee parser.LoxCallable, args []domain.Value) (domain.Value, error) {
+	if err := i.allocateCall(callee, args); err != nil {
+		return nil, err
+	}
+	if err := i.pushFrame(callee, args); err != nil {
+		return nil, err
+	}
+	result, err := callee.Call(i, args)
+
+	tailCalls := 0
+	for {
+		tc, isTailCall := err.(tailCall)
+		if !isTailCall {
+			break
+		}
+		if err = i.allocateCall(tc.callee, tc.args); err != nil {
+			break
+		}
+		i.replaceFrame(tc.callee, tc.args)
+		tailCalls++
+		result, err = tc.callee.Call(i, tc.args)
+	}
+
+	// Call hooks see each tail call finish as if it were nested, as each call returns the result of the next
+	for ; tailCalls > 0; tailCalls-- {
+		i.exitCallHooks(result, err)
+	}
+	i.popFrame(result, err)
+	return result, err
+}
+
+// allocateCall accounts for the environment of a call, and the instance when constructing one
+func (i *Interpreter) allocateCall(callee parser.LoxCallable, args []domain.Value) error {
+	size := environmentSize + int64(len(args))*bindingSize
+	if _, isClass := callee.(*LoxClass); isClass {
+		size += instanceSize
+	}
+	return i.allocate(size)
 }
 
 func (i *Interpreter) VisitWhileStmt(w *parser.WhileStmt) error {
@@ -186,10 +340,12 @@ func (i *Interpreter) VisitWhileStmt(w *parser.WhileStmt) error {
 			return err
 		}
 		if !isTruthy(exprRes) {
+			i.branch(w, false)
 			break
 		}
+		i.branch(w, true)
 
-		i.evalRes, err = i.Evaluate(w.Body)
+		i.evalRes, err = i.execute(w.Body)
 		if err != nil {
 			return err
 		}
@@ -197,6 +353,42 @@ func (i *Interpreter) VisitWhileStmt(w *parser.WhileStmt) error {
 	return nil
 }
 
+// VisitForInStmt runs the body of a for-in loop for each value of its iterable, in a new environment each time so
+// that closures capture the value of their own iteration
+func (i *Interpreter) VisitForInStmt(f *parser.ForInStmt) error {
+	iterable, err := i.Evaluate(f.Iterable)
+	if err != nil {
+		return err
+	}
+	next, err := i.iterate(iterable)
+	if err != nil {
+		return err
+	}
+
+	enclosing := i.env
+	defer func() { i.env = enclosing }()
+	for {
+		value, exists, err := next()
+		if err != nil {
+			return err
+		}
+		i.branch(f, exists)
+		if !exists {
+			return nil
+		}
+
+		if err = i.allocate(environmentSize + bindingSize); err != nil {
+			return err
+		}
+		i.env = environment.NewLocalEnvironment(enclosing, i.r.ScopeSize(f))
+		i.define(f.Variable, f.Variable.Name, value)
+		i.assign(f.Variable, f.Variable.Name, value)
+		if i.evalRes, err = i.execute(f.Body); err != nil {
+			return err
+		}
+	}
+}
+
 func (i *Interpreter) VisitLogicalConjunction(c *parser.LogicalConjuction) error {
 	left, err := i.Evaluate(c.Left)
 	if err != nil {
@@ -205,9 +397,11 @@ func (i *Interpreter) VisitLogicalConjunction(c *parser.LogicalConjuction) error
 
 	if c.And { // AND case
 		if !isTruthy(left) { // short circuit
+			i.branch(c, false)
 			i.evalRes = left
 			return nil
 		}
+		i.branch(c, true)
 		right, rErr := i.Evaluate(c.Right)
 		if rErr != nil {
 			return rErr
@@ -218,9 +412,11 @@ func (i *Interpreter) VisitLogicalConjunction(c *parser.LogicalConjuction) error
 
 	// Case where OR is the conjunction
 	if isTruthy(left) { // short-circuit
+		i.branch(c, false)
 		i.evalRes = left
 		return nil
 	}
+	i.branch(c, true)
 	right, rErr := i.Evaluate(c.Right)
 	if rErr != nil {
 		return rErr
@@ -236,34 +432,37 @@ func (i *Interpreter) VisitIfStmt(ifStmt *parser.IfStmt) error {
 	}
 
 	if isTruthy(val) {
-		return ifStmt.Statement.Accept(i)
+		i.branch(ifStmt, true)
+		_, err = i.execute(ifStmt.Statement)
+		return err
 	}
+	i.branch(ifStmt, false)
 
 	if ifStmt.ElseStatement == nil {
 		return nil
 	}
 
-	return ifStmt.ElseStatement.Accept(i)
+	_, err = i.execute(ifStmt.ElseStatement)
+	return err
 }
 
 func (i *Interpreter) VisitBlock(b *parser.Block) error {
-	// Create a new environment for execution of Block b
-	oldEnv := i.env
-	i.env = &environment.Environment{
-		Enclosing: oldEnv,
-		Values:    map[string]domain.Value{},
+	if err := i.allocate(environmentSize); err != nil {
+		return err
 	}
-	defer func() {
-		i.env = oldEnv
-	}()
+	// Create a new environment for execution of Block b
+	return i.ExecuteBlock(b, environment.NewLocalEnvironment(i.env, i.r.ScopeSize(b)))
+}
 
-	for _, stmt := range b.Statements {
-		result, err := i.Evaluate(stmt)
+// executeStatements executes each statement in turn within the current environment
+func (i *Interpreter) executeStatements(stmts []parser.Node) error {
+	for _, stmt := range stmts {
+		result, err := i.execute(stmt)
 		if err != nil {
 			return err
 		}
-		if i.replMode && result != nil { // only print our statements which evaluate to a Value
-			fmt.Println("evaluates to:", result)
+		if err = i.printResult(result); err != nil {
+			return err
 		}
 	}
 	return nil
@@ -278,13 +477,14 @@ func (i *Interpreter) VisitAssignment(a *parser.Assignment) error {
 
 	// Check if the variable is a local variable, if so set it in the local environment
 	// otherwise set it in the global environment
-	if dist, err := i.r.GetLocal(a); err == nil {
-		if err = i.env.SetAt(dist, a.TokenName, v); err != nil {
+	if local, isLocal := i.r.GetLocal(a); isLocal {
+		if err = i.env.SetAt(local.Depth, local.Slot, v); err != nil {
 			return err
 		}
 	} else if err = i.globals.Set(a.TokenName, v); err != nil {
 		return err
 	}
+	i.assign(a, a.TokenName, v)
 	i.evalRes = v
 	return nil
 }
@@ -307,17 +507,24 @@ func (i *Interpreter) VisitVarStmt(v *parser.VarStmt) error {
 			return err
 		}
 	}
-	i.env.Define(v.Name, initializer)
+	if err = i.allocate(bindingSize); err != nil {
+		return err
+	}
+	i.define(v, v.Name, initializer)
+	i.assign(v, v.Name, initializer)
 	return nil
 }
 
 func (i *Interpreter) VisitPrintStmt(p *parser.PrintStmt) error {
-	err := p.Arg.Accept(i)
+	val, err := i.Evaluate(p.Arg)
 	if err != nil {
 		return err
 	}
-	fmt.Println(i.evalRes)
-	i.evalRes = nil
+	text, err := i.Stringify(val)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(i.output(), text)
 	return nil
 }
 
@@ -330,6 +537,18 @@ func (i *Interpreter) VisitBinary(b *parser.Binary) error {
 	if err != nil {
 		return err
 	}
+	_, leftIsInstance := left.(*LoxInstance)
+	_, rightIsInstance := right.(*LoxInstance)
+	if leftIsInstance || rightIsInstance {
+		result, overloaded, err := i.binaryOverload(b.Operator, left, right)
+		if err != nil {
+			return err
+		}
+		if overloaded {
+			i.evalRes = result
+			return nil
+		}
+	}
 	switch b.Operator.Type {
 	case lexer.MINUS:
 		if err = i.validateBothNumber(left, right); err != nil {
@@ -350,6 +569,9 @@ func (i *Interpreter) VisitBinary(b *parser.Binary) error {
 		if i.validateBothNumber(left, right) == nil {
 			i.evalRes = left.(float64) + right.(float64)
 		} else if i.validateBothString(left, right) == nil {
+			if err = i.allocate(stringSize + int64(len(left.(string))+len(right.(string)))); err != nil {
+				return err
+			}
 			i.evalRes = left.(string) + right.(string)
 		} else {
 			return fmt.Errorf("could not use + on values that are not both strings or numbers, values: '%v', '%v'", left, right)
@@ -373,7 +595,12 @@ func (i *Interpreter) VisitBinary(b *parser.Binary) error {
 		if err = i.validateBothNumber(left, right); err != nil {
 			return err
 		}
-		i.evalRes = left.(float64) <= right.(float64)
+		i.evalRes = left.(float64) >= right.(float64)
+	case lexer.INSTANCEOF:
+		i.evalRes, err = instanceOf(left, right)
+		if err != nil {
+			return err
+		}
 	case lexer.EQUAL_EQUAL:
 		i.evalRes = isEqual(left, right)
 	case lexer.BANG_EQUAL:
@@ -406,13 +633,17 @@ func (i *Interpreter) VisitUnary(u *parser.Unary) error {
 
 	switch u.Operator.Type {
 	case lexer.MINUS:
+		if instance, isInstance := i.evalRes.(*LoxInstance); isInstance {
+			i.evalRes, err = i.negateOverload(instance)
+			return err
+		}
 		val, ok := i.evalRes.(float64)
 		if !ok {
 			return fmt.Errorf("expected number with unary operator, had '%+v' instead", val)
 		}
 		i.evalRes = -val
 	case lexer.BANG:
-		i.evalRes = isTruthy(i.evalRes)
+		i.evalRes = !isTruthy(i.evalRes)
 	default:
 		return fmt.Errorf("unexpected operator type in unary: %+v", u)
 	}
@@ -464,6 +695,8 @@ func (i *Interpreter) validateBothNumber(left domain.Value, right domain.Value)
 	// not when passing interface types.
 	// Another note is that benchmarks show no difference in validating via type assertions and then re-asserting
 	// for using the value - the compiler must be optimizing that for us in any case
+	// See BenchmarkValidateThenCast and BenchmarkValidateTaggedValue, and the internal/bench package for benchmarks
+	// of whole programs
 	_, ok := left.(float64)
 	if !ok {
 		return fmt.Errorf("%v is not a number", left)
@@ -495,7 +728,7 @@ func (i *Interpreter) VisitSetExpr(s *parser.SetExpr) error {
 		return err
 	}
 
-	instance, ok := instanceRes.(LoxInstance)
+	instance, ok := instanceRes.(*LoxInstance)
 	if !ok {
 		return fmt.Errorf("Expected instance of type LoxInstance, but got '%v'", instanceRes)
 	}
@@ -505,6 +738,20 @@ func (i *Interpreter) VisitSetExpr(s *parser.SetExpr) error {
 		return err
 	}
 
+	if setter, exists := instance.klass.findSetter(s.Name.Lexeme); exists {
+		_, err = i.call(setter.Bind(instance), []domain.Value{evalResult})
+		return err
+	}
+	// A field would hide the getter of the same name, so a property with a getter and no setter is read-only
+	if getter, err := instance.klass.findMethod(s.Name.Lexeme); err == nil && getter.isGetter() {
+		return fmt.Errorf("Can't assign to property '%s' of class '%s', which has a getter but no setter", s.Name.Lexeme, instance.klass.Name)
+	}
+
+	if _, exists := instance.fields[s.Name.Lexeme]; !exists {
+		if err = i.allocate(bindingSize); err != nil {
+			return err
+		}
+	}
 	instance.Set(s.Name.Lexeme, evalResult)
 
 	return nil
@@ -1,6 +1,7 @@
 package interpreter
 
 import (
+	"fmt"
 	"testing"
 )
 
@@ -28,3 +29,33 @@ func BenchmarkValidateNumber(b *testing.B) {
 		_ = e.validateBothNumber(v1, v2)
 	}
 }
+
+// taggedValue is the alternative representation of values discussed in validateBothNumber, which stores the
+// type of a value alongside it rather than relying on type assertions
+type taggedValue struct {
+	isNumber bool
+	num      float64
+	str      string
+}
+
+func validateBothTaggedNumber(left, right taggedValue) error {
+	if !left.isNumber {
+		return fmt.Errorf("%v is not a number", left)
+	}
+	if !right.isNumber {
+		return fmt.Errorf("%v is not a number", right)
+	}
+	return nil
+}
+
+func BenchmarkValidateTaggedValue(b *testing.B) {
+	var res float64
+	v1 := taggedValue{isNumber: true, num: 45.}
+	v2 := taggedValue{isNumber: true, num: 46.}
+	for i := 0; i < b.N; i++ {
+		if validateBothTaggedNumber(v1, v2) == nil {
+			res = v1.num - v2.num
+		}
+	}
+	nop(res)
+}
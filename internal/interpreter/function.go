@@ -8,33 +8,45 @@ import (
 	"github.com/levpaul/glocks/internal/parser"
 )
 
+// thisSlot is the slot of 'this' in the environment a method is bound in
+const thisSlot = 0
+
 type LoxFunction struct {
 	declaration   *parser.FunctionDeclaration
 	closure       *environment.Environment
 	isInitializer bool
+	// scopeSize is the number of variables the function's environment holds, including its parameters
+	scopeSize int
+	// className is the name of the class a method belongs to, and is empty for functions
+	className string
+	// isGenerator is whether the function yields, so that calling it returns a generator running its body
+	isGenerator bool
 }
 
 // Call executes a Lox function with the given interpreter and arguments.
 // It creates a new environment with the function's closure as the enclosing scope,
-// binds the function parameters to the provided argument values,
+// binds the function parameters to the provided argument values, or their defaults,
 // and executes the function body.
 func (l LoxFunction) Call(i parser.LoxInterpreter, args []domain.Value) (domain.Value, error) {
-	env := environment.NewEnvironment(l.closure)
-	for idx, p := range l.declaration.Params {
-		env.Define(p, args[idx])
+	env := environment.NewLocalEnvironment(l.closure, l.scopeSize)
+	if err := i.(*Interpreter).bindArguments(l, env, args); err != nil {
+		return nil, err
+	}
+	if l.isGenerator {
+		return i.(*Interpreter).newGenerator(l, env)
 	}
 
 	blockErr := i.ExecuteBlock(&parser.Block{Statements: l.declaration.Body}, env)
 	if blockErr == nil {
 		if l.isInitializer {
-			return l.closure.GetAt(0, "this")
+			return l.closure.GetAt(0, thisSlot)
 		}
 		return nil, nil
 	}
 
 	if earlyReturn, isEarlyReturn := blockErr.(EarlyReturn); isEarlyReturn {
 		if l.isInitializer {
-			return l.closure.GetAt(0, "this")
+			return l.closure.GetAt(0, thisSlot)
 		}
 		return earlyReturn.result, nil
 	}
@@ -42,9 +54,26 @@ func (l LoxFunction) Call(i parser.LoxInterpreter, args []domain.Value) (domain.
 	return nil, blockErr
 }
 
-// Arity returns the number of parameters a function has.
-func (l LoxFunction) Arity() int {
-	return len(l.declaration.Params)
+// isGetter reports whether the function is a getter, which is called as soon as its property is read
+func (l LoxFunction) isGetter() bool {
+	return l.declaration.Getter
+}
+
+// Arity returns the number of arguments a function accepts: at least one for each parameter without a default,
+// and at most one for each parameter, or any number more when it has a rest parameter.
+func (l LoxFunction) Arity() parser.Arity {
+	params := len(l.declaration.Params)
+	if l.declaration.Rest {
+		params--
+	}
+	required := params
+	for required > 0 && l.declaration.Default(required-1) != nil {
+		required--
+	}
+	if l.declaration.Rest {
+		return parser.Arity{Min: required, Max: parser.Variadic}
+	}
+	return parser.Arity{Min: required, Max: params}
 }
 
 // String returns a string representation of the function.
@@ -52,12 +81,16 @@ func (l LoxFunction) String() string {
 	return fmt.Sprintf("<fn %s>", l.declaration.Name)
 }
 
-func (l LoxFunction) Bind(instance LoxInstance) LoxFunction {
-	env := environment.NewEnvironment(l.closure)
-	env.Define("this", instance)
+// Bind returns the method bound to 'this', which is an instance for methods and a class for class methods
+func (l LoxFunction) Bind(this domain.Value) LoxFunction {
+	env := environment.NewLocalEnvironment(l.closure, 1)
+	env.DefineAt(thisSlot, "this", this)
 	return LoxFunction{
 		declaration:   l.declaration,
 		closure:       env,
 		isInitializer: l.isInitializer,
+		scopeSize:     l.scopeSize,
+		className:     l.className,
+		isGenerator:   l.isGenerator,
 	}
 }
@@ -0,0 +1,257 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+
+	"github.com/levpaul/glocks/internal/domain"
+	"github.com/levpaul/glocks/internal/environment"
+	"github.com/levpaul/glocks/internal/parser"
+)
+
+// errGeneratorClosed unwinds the body of a generator which was closed while suspended at a yield
+var errGeneratorClosed = errors.New("generator was closed")
+
+// LoxGenerator is the result of calling a generator function, a function which yields. It runs the function's
+// body up to each yield as values are asked for, through its hasNext() and next() methods or by looping over it
+// with for-in, and close() abandons the rest of the body.
+//
+// The body runs on a goroutine of its own, so that it can be suspended part way through with its Go stack intact,
+// but only ever while the goroutine resuming it waits for it to yield. So only one goroutine runs Lox code at a
+// time, and the interpreter's state needs no locking.
+type LoxGenerator struct {
+	*generator
+}
+
+// generator is the state of a generator. It's kept apart from LoxGenerator, which scripts hold, so that the
+// goroutine running the body doesn't keep the LoxGenerator reachable. Once a script can no longer reach a
+// LoxGenerator, it's queued to be closed and its goroutine ends.
+type generator struct {
+	fn LoxFunction
+	// env is the innermost environment of the body, where it continues from when resumed
+	env *environment.Environment
+	// resume hands control to the body's goroutine, to continue it when true or to close it when false
+	resume chan bool
+	// yields hands control back from the body's goroutine, with each value it yields and then once it is done
+	yields  chan yielded
+	started bool
+	done    bool
+	// closing is whether the generator was closed by its own body, which ends it at its next yield
+	closing bool
+	// next is a value yielded ahead of being asked for, when hasNext() had to run the body to find it
+	next     domain.Value
+	hasValue bool
+}
+
+// yielded is what a generator's body hands back when it suspends, either a value or that it finished
+type yielded struct {
+	value domain.Value
+	done  bool
+	err   error
+}
+
+// newGenerator allocates a generator which runs the body of fn in env, which holds its arguments. The body doesn't
+// start running until its first value is asked for.
+func (i *Interpreter) newGenerator(fn LoxFunction, env *environment.Environment) (*LoxGenerator, error) {
+	i.closeAbandonedGenerators()
+	if err := i.allocate(generatorSize); err != nil {
+		return nil, err
+	}
+	g := &LoxGenerator{&generator{
+		fn:     fn,
+		env:    env,
+		resume: make(chan bool),
+		yields: make(chan yielded),
+	}}
+	runtime.SetFinalizer(g, func(g *LoxGenerator) {
+		i.abandonedMu.Lock()
+		defer i.abandonedMu.Unlock()
+		i.abandoned = append(i.abandoned, g.generator)
+	})
+	return g, nil
+}
+
+func (g *LoxGenerator) String() string {
+	return fmt.Sprintf("<generator %s>", g.fn.declaration.Name)
+}
+
+// Get returns one of the generator's methods bound to it
+func (g *LoxGenerator) Get(name string) (domain.Value, error) {
+	switch name {
+	case hasNextMethod:
+		return &native{name: hasNextMethod, fn: func(i *Interpreter, args []domain.Value) (domain.Value, error) {
+			return i.generatorHasNext(g.generator)
+		}}, nil
+	case nextMethod:
+		return &native{name: nextMethod, fn: func(i *Interpreter, args []domain.Value) (domain.Value, error) {
+			return i.generatorNext(g.generator)
+		}}, nil
+	case "close":
+		return &native{name: "close", fn: func(i *Interpreter, args []domain.Value) (domain.Value, error) {
+			i.closeGenerator(g.generator)
+			return nil, nil
+		}}, nil
+	}
+	return nil, fmt.Errorf("Undefined property '%s' on generator", name)
+}
+
+// generatorHasNext reports whether a generator has another value, running its body up to its next yield to find
+// out if need be
+func (i *Interpreter) generatorHasNext(g *generator) (bool, error) {
+	if !g.hasValue && !g.done {
+		y, err := i.resumeGenerator(g)
+		if err != nil || y.done {
+			return false, err
+		}
+		g.next, g.hasValue = y.value, true
+	}
+	return g.hasValue, nil
+}
+
+// generatorNext returns the next value of a generator, failing when it has none left
+func (i *Interpreter) generatorNext(g *generator) (domain.Value, error) {
+	hasNext, err := i.generatorHasNext(g)
+	if err != nil {
+		return nil, err
+	}
+	if !hasNext {
+		return nil, fmt.Errorf("generator '%s' has no more values", g.fn.declaration.Name)
+	}
+	g.hasValue = false
+	next := g.next
+	g.next = nil
+	return next, nil
+}
+
+// resumeGenerator runs the body of a generator until it next yields or finishes. Running the body is a call of
+// the generator function as far as the call stack and call hooks are concerned.
+func (i *Interpreter) resumeGenerator(g *generator) (yielded, error) {
+	if g.done {
+		return yielded{done: true}, nil
+	}
+	if i.isRunning(g) {
+		return yielded{}, fmt.Errorf("generator '%s' is already running", g.fn.declaration.Name)
+	}
+	if err := i.pushFrame(g.fn, nil); err != nil {
+		return yielded{}, err
+	}
+	if !g.started {
+		g.started = true
+		if i.liveGenerators == nil {
+			i.liveGenerators = map[*generator]bool{}
+		}
+		i.liveGenerators[g] = true
+		go g.run(i)
+	}
+
+	y := i.handOff(g, true)
+	i.popFrame(y.value, y.err)
+	return y, y.err
+}
+
+// run runs the body of a generator, on its own goroutine
+func (g *generator) run(i *Interpreter) {
+	if !<-g.resume {
+		g.yields <- yielded{done: true}
+		return
+	}
+	err := i.ExecuteBlock(&parser.Block{Statements: g.fn.declaration.Body}, g.env)
+	if _, isReturn := err.(EarlyReturn); isReturn || err == errGeneratorClosed {
+		err = nil
+	}
+	g.yields <- yielded{done: true, err: err}
+}
+
+// handOff hands control to the goroutine of a generator's body, to resume it or to close it, and waits for
+// control to be handed back. The interpreter's environment is swapped for the body's while it runs.
+func (i *Interpreter) handOff(g *generator, resume bool) yielded {
+	callerEnv := i.env
+	i.env = g.env
+	if resume {
+		i.generators = append(i.generators, g)
+	}
+
+	g.resume <- resume
+	y := <-g.yields
+
+	if resume {
+		i.generators = i.generators[:len(i.generators)-1]
+	}
+	g.env = i.env
+	i.env = callerEnv
+	if y.done {
+		g.done = true
+		delete(i.liveGenerators, g)
+	}
+	return y
+}
+
+// VisitYieldStmt hands the value of a yield to whatever resumed the generator it is within, and waits for the
+// generator to be resumed again
+func (i *Interpreter) VisitYieldStmt(y *parser.YieldStmt) error {
+	var value domain.Value
+	if y.Expression != nil {
+		var err error
+		if value, err = i.Evaluate(y.Expression); err != nil {
+			return err
+		}
+	}
+
+	g := i.generators[len(i.generators)-1]
+	if g.closing {
+		return errGeneratorClosed
+	}
+	g.yields <- yielded{value: value}
+	if !<-g.resume {
+		return errGeneratorClosed
+	}
+	i.evalRes = nil
+	return nil
+}
+
+// closeGenerator abandons the rest of a generator's body, ending its goroutine. Closing a generator which is
+// running, as its own body may, takes effect once it next yields.
+func (i *Interpreter) closeGenerator(g *generator) {
+	if g.done {
+		return
+	}
+	if !g.started {
+		g.done = true
+		return
+	}
+	if i.isRunning(g) {
+		g.closing = true
+		return
+	}
+	i.handOff(g, false)
+}
+
+// isRunning reports whether a generator's body is running, rather than suspended
+func (i *Interpreter) isRunning(g *generator) bool {
+	for _, running := range i.generators {
+		if running == g {
+			return true
+		}
+	}
+	return false
+}
+
+// closeAbandonedGenerators closes the generators which scripts can no longer reach
+func (i *Interpreter) closeAbandonedGenerators() {
+	i.abandonedMu.Lock()
+	abandoned := i.abandoned
+	i.abandoned = nil
+	i.abandonedMu.Unlock()
+
+	for _, g := range abandoned {
+		i.closeGenerator(g)
+	}
+}
+
+// closeGenerators closes every generator which has been started but not finished
+func (i *Interpreter) closeGenerators() {
+	for g := range i.liveGenerators {
+		i.closeGenerator(g)
+	}
+}
@@ -0,0 +1,76 @@
+package interpreter
+
+import (
+	"io"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const infiniteGenerator = `
+fun naturals() {
+  var n = 0;
+  while (true) {
+    yield n;
+    n = n + 1;
+  }
+}`
+
+// eventually waits for a condition which becomes true once goroutines finish, or the garbage collector runs. It
+// polls on the test's own goroutine, so as not to count towards runtime.NumGoroutine.
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		runtime.GC()
+		if condition() {
+			return
+		}
+	}
+	t.Fatal("condition was never satisfied")
+}
+
+func TestUnfinishedGeneratorsAreClosedAfterRun(t *testing.T) {
+	// Generators register finalizers, which run on a goroutine of their own once the first is registered
+	_, err := runWithOutput(infiniteGenerator + `naturals();`)
+	require.NoError(t, err)
+	before := runtime.NumGoroutine()
+	out, err := runWithOutput(infiniteGenerator + `
+var a = naturals();
+var b = naturals();
+print a.next();
+print b.next();
+print b.next();`)
+	require.NoError(t, err)
+	assert.Equal(t, "0\n0\n1\n", out)
+	eventually(t, func() bool { return runtime.NumGoroutine() <= before })
+}
+
+func TestClosingGeneratorEndsItsGoroutine(t *testing.T) {
+	i := New(zap.NewNop().Sugar(), WithStdout(io.Discard))
+	i.replMode = true
+	require.NoError(t, i.Run(infiniteGenerator+`var g = naturals(); g.next();`))
+	assert.Len(t, i.liveGenerators, 1)
+
+	require.NoError(t, i.Run(`g.close(); print g.hasNext();`))
+	assert.Empty(t, i.liveGenerators)
+}
+
+func TestAbandonedGeneratorsAreClosed(t *testing.T) {
+	i := New(zap.NewNop().Sugar(), WithStdout(io.Discard))
+	i.replMode = true
+	require.NoError(t, i.Run(infiniteGenerator+`var g = naturals(); g.next(); g = nil;`))
+	assert.Len(t, i.liveGenerators, 1)
+
+	eventually(t, func() bool {
+		i.abandonedMu.Lock()
+		defer i.abandonedMu.Unlock()
+		return len(i.abandoned) == 1
+	})
+	// Abandoned generators are closed whenever another is created
+	require.NoError(t, i.Run(`var h = naturals();`))
+	assert.Empty(t, i.liveGenerators)
+}
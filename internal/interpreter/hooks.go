@@ -0,0 +1,124 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/levpaul/glocks/internal/domain"
+	"github.com/levpaul/glocks/internal/environment"
+	"github.com/levpaul/glocks/internal/lexer"
+	"github.com/levpaul/glocks/internal/parser"
+	"github.com/levpaul/glocks/internal/resolver"
+)
+
+// StatementHook is notified before the interpreter executes each statement, which allows tools like a
+// debugger to pause execution. Returning an error aborts the program with that error.
+type StatementHook interface {
+	BeforeStatement(stmt parser.Node) error
+}
+
+// CallHook is notified when a call to a function, method, class or native function starts and finishes, which
+// allows tools like a profiler to attribute time to functions. Calls always finish in the reverse order they
+// started, including calls which fail with err. The declaration is where the function was declared, which is
+// unknown for classes and natives.
+type CallHook interface {
+	EnterCall(name string, declaration lexer.Position, args []domain.Value)
+	ExitCall(result domain.Value, err error)
+}
+
+// AssignHook is notified each time a variable is given a value, either by a *parser.VarStmt declaring it or a
+// *parser.Assignment
+type AssignHook interface {
+	Assign(node parser.Node, name string, value domain.Value)
+}
+
+// assign notifies any assign hooks of a variable being given a value
+func (i *Interpreter) assign(node parser.Node, name string, value domain.Value) {
+	for _, h := range i.assignHooks {
+		h.Assign(node, name, value)
+	}
+}
+
+// BranchHook is notified each time the interpreter decides which way to branch, which allows tools to record
+// branch coverage. For an *parser.IfStmt or *parser.WhileStmt, taken is whether the condition was truthy, and for
+// a *parser.ForInStmt whether there was another value to loop over. For a *parser.LogicalConjuction, taken is
+// whether the right operand was evaluated, rather than short-circuiting.
+type BranchHook interface {
+	Branch(node parser.Node, taken bool)
+}
+
+// branch notifies any branch hooks of the way a branch went
+func (i *Interpreter) branch(node parser.Node, taken bool) {
+	for _, h := range i.branchHooks {
+		h.Branch(node, taken)
+	}
+}
+
+// execute runs a single statement, notifying any statement hooks before it is evaluated
+func (i *Interpreter) execute(stmt parser.Node) (domain.Value, error) {
+	if err := i.step(); err != nil {
+		return nil, err
+	}
+	if stmt != nil {
+		i.frames[len(i.frames)-1].pos = stmt.Position()
+	}
+	for _, h := range i.stmtHooks {
+		if err := h.BeforeStatement(stmt); err != nil {
+			return nil, err
+		}
+	}
+	return i.Evaluate(stmt)
+}
+
+// EvaluateIn runs Lox code within the given environment, typically the environment of a paused frame, and
+// returns the value of the last statement. As the code was never seen by the resolver, variables, 'this' and
+// 'super' are bound by searching the slots of env for their names, falling back to globals, and forgotten again
+// once the code has run.
+func (i *Interpreter) EvaluateIn(code string, env *environment.Environment) (domain.Value, error) {
+	tokens := lexer.NewScanner(code, i.log).ScanTokens()
+	stmts, err := parser.NewParser(i.log, tokens).Parse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse line, err='%w'", err)
+	}
+
+	var bound []parser.Node
+	parser.InspectAll(stmts, func(node parser.Node) bool {
+		var name string
+		switch n := node.(type) {
+		case *parser.Variable:
+			name = n.TokenName
+		case *parser.Assignment:
+			name = n.TokenName
+		case *parser.ThisExpr:
+			name = n.Keyword.Lexeme
+		case *parser.SuperExpr:
+			name = n.Keyword.Lexeme
+		default:
+			return true
+		}
+		for depth, e := 0, env; e != nil && e != i.globals; depth, e = depth+1, e.Enclosing {
+			if slot, found := e.SlotOf(name); found {
+				i.r.SetLocal(node, resolver.Local{Depth: depth, Slot: slot})
+				bound = append(bound, node)
+				break
+			}
+		}
+		return true
+	})
+	defer func() {
+		for _, node := range bound {
+			i.r.DeleteLocal(node)
+		}
+	}()
+
+	oldEnv := i.env
+	i.env = env
+	defer func() { i.env = oldEnv }()
+
+	var result domain.Value
+	for _, stmt := range stmts {
+		if result, err = i.Evaluate(stmt); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
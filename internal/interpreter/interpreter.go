@@ -1,61 +1,202 @@
 package interpreter
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"os"
+	"sync"
 
 	"github.com/levpaul/glocks/internal/builtins"
 	"github.com/levpaul/glocks/internal/domain"
 	"github.com/levpaul/glocks/internal/environment"
 	"github.com/levpaul/glocks/internal/lexer"
+	"github.com/levpaul/glocks/internal/optimizer"
 	"github.com/levpaul/glocks/internal/parser"
 	"github.com/levpaul/glocks/internal/resolver"
 	"go.uber.org/zap"
 )
 
+// Option configures optional behaviour of an Interpreter
+type Option func(*Interpreter)
+
+// WithStatementHook registers a hook which is notified before every statement the interpreter executes
+func WithStatementHook(h StatementHook) Option {
+	return func(i *Interpreter) {
+		i.stmtHooks = append(i.stmtHooks, h)
+	}
+}
+
+// WithCallHook registers a hook which is notified as every call starts and finishes
+func WithCallHook(h CallHook) Option {
+	return func(i *Interpreter) {
+		i.callHooks = append(i.callHooks, h)
+	}
+}
+
+// WithBranchHook registers a hook which is notified of the way every branch goes
+func WithBranchHook(h BranchHook) Option {
+	return func(i *Interpreter) {
+		i.branchHooks = append(i.branchHooks, h)
+	}
+}
+
+// WithAssignHook registers a hook which is notified every time a variable is given a value
+func WithAssignHook(h AssignHook) Option {
+	return func(i *Interpreter) {
+		i.assignHooks = append(i.assignHooks, h)
+	}
+}
+
+// WithStdout sets where the output of print statements is written, which is os.Stdout by default
+func WithStdout(w io.Writer) Option {
+	return func(i *Interpreter) {
+		i.stdout = w
+	}
+}
+
+// WithCapabilities grants the capabilities whose native functions scripts may call, replacing the
+// builtins.DefaultCapabilities. Natives of capabilities which aren't granted are never defined.
+func WithCapabilities(caps ...builtins.Capability) Option {
+	return func(i *Interpreter) {
+		i.capabilities = append([]builtins.Capability{}, caps...)
+	}
+}
+
+// WithOptimization runs the optimizer over each program after it is resolved, folding constant expressions and
+// removing code which can never run
+func WithOptimization() Option {
+	return func(i *Interpreter) {
+		i.optimize = true
+	}
+}
+
+// RuntimeError is returned when a program fails during evaluation, as opposed to failing to scan, parse or resolve
+type RuntimeError struct {
+	Err error
+}
+
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("failed to evaluate expression: '%v'", e.Err)
+}
+
+func (e *RuntimeError) Unwrap() error {
+	return e.Err
+}
+
 // New creates a new Interpreter for Lox
-func New(log *zap.SugaredLogger) *Interpreter {
-	globals := newGlobalEnv()
-	return &Interpreter{
-		log:        log,
-		s:          nil,
-		p:          nil,
-		astPrinter: parser.ExprPrinter{},
-		replMode:   false,
-		globals:    globals,
-		env:        globals, // Set initial env to Global
-		r:          resolver.NewResolver(),
+func New(log *zap.SugaredLogger, opts ...Option) *Interpreter {
+	i := &Interpreter{
+		log:      log,
+		s:        nil,
+		p:        nil,
+		replMode: false,
+		r:        resolver.NewResolver(),
+		frames:   []*frame{{name: "<script>"}},
+		ctx:      context.Background(),
+
+		capabilities: builtins.DefaultCapabilities,
+		maxCallDepth: DefaultMaxCallDepth,
+	}
+	for _, opt := range opts {
+		opt(i)
 	}
+	i.globals = newGlobalEnv(i.capabilities)
+	i.env = i.globals // Set initial env to Global
+	return i
 }
 
 // Interpreter is the main struct for the Lox interpreter, it is self-contained and
 // can be used to run a Lox program.
 type Interpreter struct {
-	log        *zap.SugaredLogger
-	s          *lexer.Scanner
-	p          *parser.Parser
-	r          *resolver.Resolver
-	astPrinter parser.ExprPrinter
-	replMode   bool
-	globals    *environment.Environment
-	env        *environment.Environment
-	evalRes    any
-}
-
-func newGlobalEnv() *environment.Environment {
+	log      *zap.SugaredLogger
+	s        *lexer.Scanner
+	p        *parser.Parser
+	r        *resolver.Resolver
+	replMode bool
+	globals  *environment.Environment
+	env      *environment.Environment
+	evalRes  any
+	// frames is the call stack, with the top level of the script as the first frame
+	frames      []*frame
+	stmtHooks   []StatementHook
+	callHooks   []CallHook
+	branchHooks []BranchHook
+	assignHooks []AssignHook
+	// capabilities are those granted to scripts, deciding which native functions are defined
+	capabilities []builtins.Capability
+	// stdout is where print statements write to, when nil os.Stdout is used
+	stdout io.Writer
+	// optimize is whether programs are optimized before they are run
+	optimize bool
+	// stringifying are the instances whose toString method is running, and the lists being stringified, which
+	// are shown without stringifying them again when they contain themselves
+	stringifying map[any]bool
+	// generators are the generators whose bodies are running, innermost last, and liveGenerators those which
+	// have started and not yet finished. abandoned are those queued to be closed once scripts can't reach them.
+	generators     []*generator
+	liveGenerators map[*generator]bool
+	abandonedMu    sync.Mutex
+	abandoned      []*generator
+
+	// ctx is the context of the current run, which aborts execution when cancelled
+	ctx context.Context
+	// steps is the number of statements executed in the current run, limited by maxSteps when it is non-zero
+	steps        int
+	maxSteps     int
+	maxCallDepth int
+	mem          memoryAccount
+}
+
+// newGlobalEnv creates the global environment, defining the native functions of each granted capability
+func newGlobalEnv(caps []builtins.Capability) *environment.Environment {
 	g := &environment.Environment{Values: map[string]domain.Value{}}
 
-	g.Define("clock", &builtins.Clock{})
+	granted := map[builtins.Capability]bool{}
+	for _, c := range caps {
+		granted[c] = true
+	}
+	for _, n := range builtins.Natives {
+		if n.Capability == "" || granted[n.Capability] {
+			g.Define(n.Name, n.Fn)
+		}
+	}
+	for _, n := range interpreterNatives {
+		g.Define(n.name, n)
+	}
 
 	return g
 }
 
+// output returns the writer for program output, looking up os.Stdout at the time of writing by default
+func (i *Interpreter) output() io.Writer {
+	if i.stdout == nil {
+		return os.Stdout
+	}
+	return i.stdout
+}
+
 // GetEnvironment returns the current environment of the interpreter
 func (i *Interpreter) GetEnvironment() *environment.Environment {
 	return i.env
 }
 
+// Context returns the context of the current run, or context.Background() between runs
+func (i *Interpreter) Context() context.Context {
+	return i.ctx
+}
+
 // Run executes a Lox program.
 func (i *Interpreter) Run(program string) error {
+	return i.RunContext(context.Background(), program)
+}
+
+// RunContext executes a Lox program, aborting with the context's error if it is cancelled before the program
+// finishes.
+func (i *Interpreter) RunContext(ctx context.Context, program string) error {
+	i.ctx = ctx
+	defer func() { i.ctx = context.Background() }()
+
 	var err error
 	if err = i.run(program); err != nil {
 		i.log.With("error", err).
@@ -70,6 +211,14 @@ func (i *Interpreter) Run(program string) error {
 // run executes Lox code. It splits the code into tokens, parses the tokens into an AST,
 // resolves variable names to their scope, and then evaluates the AST.
 func (i *Interpreter) run(code string) error {
+	i.steps = 0
+	i.resetMemory()
+	// The REPL keeps its generators between lines, but the bodies of those left unfinished by a script are
+	// abandoned once it ends
+	if !i.replMode {
+		defer i.closeGenerators()
+	}
+
 	// Run a lexer on the line of code to tokenize it
 	i.s = lexer.NewScanner(code, i.log)
 	tokens := i.s.ScanTokens()
@@ -90,40 +239,55 @@ func (i *Interpreter) run(code string) error {
 	if err != nil {
 		return fmt.Errorf("static analysis [resolver] FAILURE, err='%w'", err)
 	}
+	if i.optimize {
+		stmts = optimizer.Optimize(stmts)
+	}
 
 	// Execute each statement in the program, via AST traversal of the parsed statements
 	for _, stmt := range stmts {
-		if i.replMode && i.log.Level() <= zap.DebugLevel {
-			i.log.Debugf("AST repr of input: %s", i.astPrinter.Print(stmt))
-		}
-
-		result, err := i.Evaluate(stmt)
+		result, err := i.execute(stmt)
 		if err != nil {
 			if _, isEarlyRet := err.(EarlyReturn); isEarlyRet {
 				return fmt.Errorf("unexpected 'return' expression found. Expected to be within a function")
 			}
-			return fmt.Errorf("failed to evaluate expression: '%w'", err)
+			return &RuntimeError{Err: err}
 		}
-		if i.replMode && result != nil { // only print our statements which evaluate to a Value
-			fmt.Println("evaluates to:", result)
+		if err = i.printResult(result); err != nil {
+			return &RuntimeError{Err: err}
 		}
 	}
 
+	i.measureMemory(0)
 	return nil
 }
 
-// ExecuteBlock takes a Block and an Environment, executing Block with specific Environment env
+// ExecuteBlock takes a Block and an Environment, executing the statements of Block directly within env
 func (i *Interpreter) ExecuteBlock(block *parser.Block, env *environment.Environment) error {
 	oldEnv := i.env
 	i.env = env
 	defer func() { i.env = oldEnv }()
-	return i.VisitBlock(block)
+	return i.executeStatements(block.Statements)
+}
+
+// define defines the variable declared by node in the current environment, in its slot when it is a local
+func (i *Interpreter) define(node parser.Node, name string, v domain.Value) {
+	if local, isLocal := i.r.GetLocal(node); isLocal {
+		i.env.DefineAt(local.Slot, name, v)
+		return
+	}
+	i.env.Define(name, v)
 }
 
 func (i *Interpreter) lookUpVariable(name string, node parser.Node) (domain.Value, error) {
-	if distance, err := i.r.GetLocal(node); err == nil {
-		return i.env.GetAt(distance, name)
+	if local, isLocal := i.r.GetLocal(node); isLocal {
+		return i.env.GetAt(local.Depth, local.Slot)
 	}
 
-	return i.globals.Get(name)
+	val, err := i.globals.Get(name)
+	if err != nil {
+		if native, isNative := builtins.Lookup(name); isNative {
+			return nil, fmt.Errorf("native function '%s' requires the '%s' capability, which has not been granted", name, native.Capability)
+		}
+	}
+	return val, err
 }
@@ -406,3 +406,679 @@ func TestSuperWithoutSuperClass(t *testing.T) {
 	require.ErrorContains(t, err, "'super' can only be used in a subclass")
 	require.Empty(t, out)
 }
+
+func TestThisInSubclass(t *testing.T) {
+	program := `class A {}
+class B < A {
+  init(x) {
+    this.x = x;
+  }
+}
+print B(3).x;`
+	expectedOut := "3"
+	testSimpleProgramWorksWithOutput(t, program, expectedOut)
+}
+
+func TestInheritedInitializer(t *testing.T) {
+	program := `class A {
+  init(x) {
+    this.x = x;
+  }
+}
+class B < A {}
+print B(4).x;`
+	expectedOut := "4"
+	testSimpleProgramWorksWithOutput(t, program, expectedOut)
+}
+
+func TestThisInNestedFunctionsAndClasses(t *testing.T) {
+	program := `class A {}
+class B < A {
+  init(x) {
+    this.x = x;
+  }
+  getter() {
+    fun get() { return this.x; }
+    return get;
+  }
+  afterNestedClass() {
+    class C {}
+    return this.x;
+  }
+}
+var b = B(5);
+print b.getter()();
+print b.afterNestedClass();`
+	testSimpleProgramWorksWithOutput(t, program, "5\n5")
+
+	_, err := testSimpleProgram(`fun f() { return this; }`)
+	require.ErrorContains(t, err, "'this' cannot be used outside of a class")
+	_, err = testSimpleProgram(`class A { m() { class B {} } } print this;`)
+	require.ErrorContains(t, err, "'this' cannot be used outside of a class")
+}
+
+func TestInheritedInitializerFromGrandparent(t *testing.T) {
+	program := `class A {
+  init(x, y) {
+    this.sum = x + y;
+  }
+}
+class B < A {}
+class C < B {}
+print C(1, 2).sum;`
+	testSimpleProgramWorksWithOutput(t, program, "3")
+
+	_, err := testSimpleProgram(program[:len(program)-len("print C(1, 2).sum;")] + "C(1);")
+	require.ErrorContains(t, err, "Expected 2 args to be passed to func")
+}
+
+func TestDuplicateParameter(t *testing.T) {
+	program := `fun add(a, a) { return a + a; }`
+	out, err := testSimpleProgram(program)
+	require.ErrorContains(t, err, "already exists a parameter with name='a'")
+	require.Empty(t, out)
+}
+
+func TestRedeclaredLocalFunction(t *testing.T) {
+	program := `{
+  var before = "before";
+  fun f() { return 1; }
+  fun f() { return 2; }
+  var after = "after";
+  print before + " " + after;
+  print f();
+}`
+	testSimpleProgramWorksWithOutput(t, program, "before after\n2")
+}
+
+func TestComparisonAndNot(t *testing.T) {
+	cases := map[string]string{
+		`print 2 >= 1;`:   "true",
+		`print 1 >= 1;`:   "true",
+		`print 1 >= 2;`:   "false",
+		`print 1 <= 2;`:   "true",
+		`print !true;`:    "false",
+		`print !nil;`:     "true",
+		`print !0;`:       "false",
+		`print !(1 > 2);`: "true",
+
+		`var one = 1; var two = 2; print two >= one;`: "true",
+		`var one = 1; var two = 2; print one >= two;`: "false",
+		`var none = nil; print !none;`:                "true",
+		`var one = 1; print !one;`:                    "false",
+	}
+	for program, expectedOutput := range cases {
+		testSimpleProgramWorksWithOutput(t, program, expectedOutput)
+	}
+}
+
+func TestClassMethods(t *testing.T) {
+	program := `
+class Math {
+  class square(x) { return x * x; }
+  class twice(x) { return this.square(x) * 2; }
+}
+class More < Math {
+  class twice(x) { return super.twice(x) + 1; }
+  class init() { return "not an initializer"; }
+}
+print Math.square(3);
+print More.square(4);
+print More.twice(2);
+print More.init();
+var f = Math.square;
+print f(5);`
+	testSimpleProgramWorksWithOutput(t, program, "9\n16\n9\nnot an initializer\n25")
+}
+
+func TestClassMethodsAreNotInstanceMethods(t *testing.T) {
+	_, err := testSimpleProgram(`class A { class f() {} } A().f();`)
+	assert.ErrorContains(t, err, "Undefined property 'f'")
+
+	_, err = testSimpleProgram(`class A { f() {} } A.f();`)
+	assert.ErrorContains(t, err, "Undefined class method 'f' on class 'A'")
+}
+
+func TestGettersAndSetters(t *testing.T) {
+	program := `
+class Rect {
+  init(w, h) { this.w = w; this.h = h; }
+  area { return this.w * this.h; }
+  set area(v) { this.w = v / this.h; }
+}
+class Square < Rect {
+  init(s) { super.init(s, s); }
+  area { return super.area + 1; }
+  class unit { return this(1); }
+}
+var r = Rect(2, 3);
+print r.area;
+r.area = 12;
+print r.w;
+print Square(4).area;
+print Square.unit.w;
+var s = Square(2);
+s.area = 8;
+print s.w;`
+	testSimpleProgramWorksWithOutput(t, program, "6\n4\n17\n1\n4")
+}
+
+func TestAssigningGetterOnlyProperty(t *testing.T) {
+	program := `
+class A {
+  area { return 1; }
+}
+class B < A {}
+var a = A();
+print a.area;
+a.area = 2;
+print a.area;`
+	out, err := testSimpleProgram(program)
+	require.ErrorContains(t, err, "Can't assign to property 'area' of class 'A', which has a getter but no setter")
+	assert.Equal(t, "1", out)
+
+	_, err = testSimpleProgram(strings.Replace(program, "var a = A();", "var a = B();", 1))
+	require.ErrorContains(t, err, "Can't assign to property 'area' of class 'B', which has a getter but no setter")
+
+	// Fields may still hide ordinary methods
+	testSimpleProgramWorksWithOutput(t, `class A { m() { return 1; } } var a = A(); a.m = 2; print a.m;`, "2")
+}
+
+func TestOperatorOverloading(t *testing.T) {
+	program := `
+class Vec {
+  init(x, y) { this.x = x; this.y = y; }
+  __add__(o) { return Vec(this.x + o.x, this.y + o.y); }
+  __sub__(o) { return this + -o; }
+  __mul__(k) { return Vec(this.x * k, this.y * k); }
+  __div__(k) { return this * (1 / k); }
+  __eq__(o) { return this.x == o.x and this.y == o.y; }
+  __lt__(o) { return this.x < o.x; }
+  __neg__() { return Vec(-this.x, -this.y); }
+}
+var v = Vec(1, 2) + Vec(3, 4) * 2;
+print v.x;
+print v.y;
+print (v - Vec(1, 1)).y;
+print (v / 2).x;
+print Vec(1, 2) == Vec(1, 2);
+print Vec(1, 2) != Vec(1, 3);
+print Vec(1, 2) < Vec(2, 0);
+print (-v).x;`
+	testSimpleProgramWorksWithOutput(t, program, "7\n10\n9\n3.5\ntrue\ntrue\ntrue\n-7")
+}
+
+func TestOperatorOverloadingFallbacks(t *testing.T) {
+	// Without __eq__, instances are equal only to themselves
+	testSimpleProgramWorksWithOutput(t, `class P {} var p = P(); print p == p; print p == P(); print p != P();`,
+		"true\nfalse\ntrue")
+
+	_, err := testSimpleProgram(`class P {} P() < P();`)
+	assert.ErrorContains(t, err, "operator '<' is undefined for 'P instance', as class 'P' has no method '__lt__'")
+
+	_, err = testSimpleProgram(`class P {} -P();`)
+	assert.ErrorContains(t, err, "operator '-' is undefined for 'P instance', as class 'P' has no method '__neg__'")
+
+	_, err = testSimpleProgram(`class P { __add__() {} } P() + 1;`)
+	assert.ErrorContains(t, err, "operator method '__add__' of class 'P' must have 1 parameters, but has 0")
+
+	// __add__ is only used for the left operand, the right operand needs __radd__
+	_, err = testSimpleProgram(`class P { __add__(o) { return 1; } } 1 + P();`)
+	assert.ErrorContains(t, err, "operator '+' is undefined for 'P instance' on its right, as class 'P' has no method '__radd__'")
+}
+
+func TestReflectedOperatorOverloading(t *testing.T) {
+	program := `
+class Money {
+  init(n) { this.n = n; }
+  __add__(o) { return Money(this.n + o); }
+  __radd__(o) { return Money(o + this.n); }
+  __rsub__(o) { return Money(o - this.n); }
+  __rmul__(k) { return Money(k * this.n); }
+  __rdiv__(k) { return k / this.n; }
+  __lt__(o) { return this.n < o; }
+  __gt__(o) { return this.n > o; }
+  __eq__(o) { return this.n == o; }
+}
+class Other {
+  __add__(o) { return "other first"; }
+}
+print (1 + Money(2)).n;
+print (10 - Money(3)).n;
+print (3 * Money(2)).n;
+print 8 / Money(2);
+print 1 < Money(2);
+print 5 < Money(2);
+print 3 > Money(2);
+print 2 == Money(2);
+print 2 != Money(2);
+print (Money(1) + 2).n;
+print Other() + Money(1);
+Money(1) + Other();`
+	out, err := testSimpleProgram(program)
+	// Money's __add__ runs first, adding its number to Other, which has no __radd__
+	require.ErrorContains(t, err, "operator '+' is undefined for 'Other instance' on its right, as class 'Other' has no method '__radd__'")
+	assert.Equal(t, "3\n7\n6\n4\ntrue\nfalse\ntrue\ntrue\nfalse\n3\nother first", out)
+}
+
+func TestPrintFormatting(t *testing.T) {
+	cases := map[string]string{
+		`print 1000000;`:                        "1000000",
+		`print 123456789012345678;`:             "123456789012345680",
+		`print 1000000000000000000000 * 10;`:    "1e+22",
+		`print 0.1 + 0.2;`:                      "0.30000000000000004",
+		`print -2.5;`:                           "-2.5",
+		`print 0 / 0;`:                          "nan",
+		`print -1 / 0;`:                         "-inf",
+		`print nil;`:                            "nil",
+		`print str(1000000) + "," + str(true);`: "1000000,true",
+		`fun f() {} print f;`:                   "<fn f>",
+	}
+	for program, expectedOutput := range cases {
+		testSimpleProgramWorksWithOutput(t, program, expectedOutput)
+	}
+}
+
+func TestToString(t *testing.T) {
+	program := `
+class Point {
+  init(x, y) { this.x = x; this.y = y; }
+  toString() { return "(" + str(this.x) + ", " + str(this.y) + ")"; }
+}
+class Named < Point {
+  toString() { return "point " + super.toString(); }
+}
+class Plain {}
+print Point(1, 2.5);
+print str(Named(3, 4)) + "!";
+print Plain();`
+	testSimpleProgramWorksWithOutput(t, program, "(1, 2.5)\npoint (3, 4)!\nPlain instance")
+
+	// An instance is shown by default within its own toString, rather than recursing forever
+	program = `
+class Loop { toString() { return "loop of " + str(this); } }
+print Loop();`
+	testSimpleProgramWorksWithOutput(t, program, "loop of Loop instance")
+
+	_, err := testSimpleProgram(`class A { toString() { return 1; } } print A();`)
+	assert.ErrorContains(t, err, "method 'toString' of class 'A' must return a string, but returned '1'")
+}
+
+func TestType(t *testing.T) {
+	program := `
+class A { m() {} }
+trait T {}
+fun f() {}
+print type(1);
+print type("s");
+print type(true);
+print type(nil);
+print type(f);
+print type(A().m);
+print type(str);
+print type(A);
+print type(A());
+print type(T);
+print type(list());`
+	testSimpleProgramWorksWithOutput(t, program,
+		"number\nstring\nbool\nnil\nfunction\nfunction\nfunction\nclass\ninstance\ntrait\nlist")
+}
+
+func TestInstanceOf(t *testing.T) {
+	program := `
+trait T {}
+trait U with T {}
+class A {}
+class B < A with U {}
+class C {}
+var b = B();
+print b instanceof B;
+print b instanceof A;
+print b instanceof T;
+print A() instanceof B;
+print A() instanceof T;
+print b instanceof C;
+print 1 instanceof A;
+print A == A;
+print A == B;`
+	testSimpleProgramWorksWithOutput(t, program, "true\ntrue\ntrue\nfalse\nfalse\nfalse\nfalse\ntrue\nfalse")
+
+	_, err := testSimpleProgram(`class A {} A() instanceof 1;`)
+	assert.ErrorContains(t, err, "the right operand of 'instanceof' must be a class or trait, but got '1'")
+}
+
+func TestReflection(t *testing.T) {
+	program := `
+class A {
+  init() { this.b = 1; this.a = 2; }
+  m() {}
+  size { return 1; }
+}
+class B < A { n() {} }
+var b = B();
+print methods(b);
+print methods(A);
+print fields(b);
+print hasField(b, "a");
+print hasField(b, "m");
+setField(b, "c", "x");
+print getField(b, "c");
+print fields(b).length;`
+	testSimpleProgramWorksWithOutput(t, program, "[init, m, n, size]\n[init, m, size]\n[a, b]\ntrue\nfalse\nx\n3")
+
+	// Class methods are listed for classes, but not their instances, and setters for both
+	program = `
+class Math {
+  class square(x) { return x * x; }
+}
+class More < Math {
+  class cube(x) { return x * x * x; }
+  set value(v) {}
+  get() {}
+}
+print methods(Math);
+print methods(More);
+print methods(More());`
+	testSimpleProgramWorksWithOutput(t, program, "[square]\n[cube, get, square, value]\n[get, value]")
+
+	_, err := testSimpleProgram(`class A {} getField(A(), "x");`)
+	assert.ErrorContains(t, err, "Undefined field 'x' on instance of class 'A'")
+
+	_, err = testSimpleProgram(`fields(1);`)
+	assert.ErrorContains(t, err, "fields expects an instance, but got '1'")
+}
+
+func TestLists(t *testing.T) {
+	program := `
+var l = list();
+l.push(1);
+l.push("two");
+print l;
+print l.length;
+l.set(0, 3);
+print l.get(0);
+l.push(l);
+print l;`
+	testSimpleProgramWorksWithOutput(t, program, "[1, two]\n2\n3\n[3, two, [...]]")
+
+	_, err := testSimpleProgram(`var l = list(); l.push(1); l.get(1);`)
+	assert.ErrorContains(t, err, "index '1' is out of range for a list of length 1")
+
+	_, err = testSimpleProgram(`list().pop();`)
+	assert.ErrorContains(t, err, "Undefined property 'pop' on list")
+}
+
+func TestForIn(t *testing.T) {
+	program := `
+class Range {
+  init(from, to) { this.from = from; this.to = to; }
+  iterator() { return RangeIterator(this.from, this.to); }
+}
+class RangeIterator {
+  init(cur, to) { this.cur = cur; this.to = to; }
+  hasNext() { return this.cur < this.to; }
+  next() { this.cur = this.cur + 1; return this.cur - 1; }
+}
+for (var c in "hé!") print c;
+var total = 0;
+for (var n in Range(1, 4)) total = total + n;
+print total;
+for (var n in RangeIterator(7, 9)) print n;
+var l = list();
+l.push("a");
+l.push("b");
+for (var e in l) print e;
+for (var e in list()) print "never";
+var in = "in is still a name";
+print in;`
+	testSimpleProgramWorksWithOutput(t, program, "h\né\n!\n6\n7\n8\na\nb\nin is still a name")
+}
+
+func TestForInClosuresCaptureEachIteration(t *testing.T) {
+	program := `
+var closures = list();
+for (var c in "abc") {
+  fun f() { return c; }
+  closures.push(f);
+}
+for (var f in closures) print f();`
+	testSimpleProgramWorksWithOutput(t, program, "a\nb\nc")
+}
+
+func TestGenerators(t *testing.T) {
+	program := `
+fun range(n) {
+  var i = 0;
+  while (i < n) {
+    yield i;
+    i = i + 1;
+  }
+}
+for (var x in range(3)) print x;
+var g = range(2);
+print g;
+print type(g);
+print g.hasNext();
+print g.next();
+print g.next();
+print g.hasNext();
+fun doubled(s) {
+  for (var c in s) {
+    yield c;
+    yield c + c;
+  }
+  return;
+  yield "never";
+}
+for (var d in doubled("ab")) print d;
+class Tree {
+  init(l, v, r) { this.l = l; this.v = v; this.r = r; }
+  iterator() { return this.walk(); }
+  walk() {
+    if (this.l != nil) for (var v in this.l) yield v;
+    yield this.v;
+    if (this.r != nil) for (var v in this.r) yield v;
+  }
+}
+for (var v in Tree(Tree(nil, 1, nil), 2, Tree(nil, 3, nil))) print v;
+fun empty() { if (false) yield; }
+print empty().hasNext();`
+	testSimpleProgramWorksWithOutput(t, program, "0\n1\n2\n<generator range>\ngenerator\ntrue\n0\n1\nfalse\na\naa\nb\nbb\n1\n2\n3\nfalse")
+}
+
+func TestGeneratorsKeepStatePerCall(t *testing.T) {
+	program := `
+fun counter(from) {
+  var n = from;
+  while (true) {
+    yield n;
+    n = n + 1;
+  }
+}
+var a = counter(0);
+var b = counter(10);
+print a.next();
+print b.next();
+print a.next();
+print b.next();
+a.close();
+print a.hasNext();
+print b.next();`
+	testSimpleProgramWorksWithOutput(t, program, "0\n10\n1\n11\nfalse\n12")
+}
+
+func TestGeneratorErrors(t *testing.T) {
+	_, err := testSimpleProgram(`yield 1;`)
+	assert.ErrorContains(t, err, "can't yield outside of a function")
+
+	_, err = testSimpleProgram(`fun f() { yield 1; return 2; }`)
+	assert.ErrorContains(t, err, "can't return a value from generator 'f'")
+
+	_, err = testSimpleProgram(`class A { init() { yield 1; } }`)
+	assert.ErrorContains(t, err, "can't yield from the initializer")
+
+	_, err = testSimpleProgram(`fun f() { yield 1; } var g = f(); g.next(); g.next();`)
+	assert.ErrorContains(t, err, "generator 'f' has no more values")
+
+	_, err = testSimpleProgram(`var g; fun f() { yield g.next(); } g = f(); g.next();`)
+	assert.ErrorContains(t, err, "generator 'f' is already running")
+
+	_, err = testSimpleProgram(`fun f() { yield 1; yield nil + 1; } for (var x in f()) print x;`)
+	assert.ErrorContains(t, err, "could not use + on values")
+}
+
+func TestDefaultAndRestParameters(t *testing.T) {
+	program := `
+fun f(a, b = a * 2, ...rest) {
+  print str(a) + " " + str(b) + " " + str(rest);
+}
+f(1);
+f(1, 5);
+f(1, 5, 6, 7);
+var calls = 0;
+fun next() {
+  calls = calls + 1;
+  return calls;
+}
+fun g(x = next()) { return x; }
+print g();
+print g(10);
+print g();
+fun count(...xs) { return xs.length; }
+print count();
+print count(1, 2, 3);
+class Point {
+  init(x, y = 0) { this.x = x; this.y = y; }
+}
+print Point(3).y;
+print list(1, 2).length;`
+	testSimpleProgramWorksWithOutput(t, program, "1 2 []\n1 5 []\n1 5 [6, 7]\n1\n10\n2\n0\n3\n0\n2")
+}
+
+func TestNamedArguments(t *testing.T) {
+	program := `
+fun greet(name, greeting = "hello", punctuation = "!") {
+  return greeting + " " + name + punctuation;
+}
+print greet("bob", punctuation: "?");
+print greet(punctuation: ".", name: "al", greeting: "hi");
+fun f(a, b = 2, c = 3) { return str(a) + str(b) + str(c); }
+print f(1, c: 9);
+class Point {
+  init(x, y) { this.x = x; this.y = y; }
+}
+var p = Point(y: 2, x: 1);
+print str(p.x) + "," + str(p.y);`
+	testSimpleProgramWorksWithOutput(t, program, "hello bob?\nhi al.\n129\n1,2")
+}
+
+func TestArgumentErrors(t *testing.T) {
+	cases := map[string]string{
+		`fun f(a, b) {} f(1);`:                  "Expected 2 args to be passed to func, but received 1.",
+		`fun f(a, b = 1) {} f(1, 2, 3);`:        "Expected 1 to 2 args to be passed to func, but received 3.",
+		`fun f(a, ...r) {} f();`:                "Expected at least 1 args to be passed to func, but received 0.",
+		`fun f(a, b) {} f(1, c: 2);`:            "'f' has no parameter named 'c'",
+		`fun f(a, b) {} f(1, a: 2);`:            "argument 'a' of 'f' is given more than once",
+		`fun f(a, b) {} f(1, 2, b: 3);`:         "argument 'b' of 'f' is given more than once",
+		`fun f(a, b) {} f(b: 2);`:               "missing argument 'a' of 'f'",
+		`fun f(a, ...r) {} f(r: 1);`:            "rest parameter 'r' of 'f' can't be given by name",
+		`str(value: 1);`:                        "'str' doesn't take named arguments",
+		`fun f(a) {} f(a: 1, 2);`:               "Positional arguments can't follow named arguments",
+		`fun f(a) {} f(a: 1, a: 2);`:            "Argument 'a' is named more than once",
+		`fun f(a = 1, b) {}`:                    "parameter 'b' without a default value can't follow one with a default",
+		`fun f(...a, b) {}`:                     "rest parameter 'a' must be the last parameter",
+		`fun f(...a = 1) {}`:                    "rest parameter 'a' can't have a default value",
+		`class A { set x(...v) {} }`:            "setter 'x' must have exactly one parameter",
+		`fun f(a, b = missing) {} f(1);`:        "attempted to get variable 'missing' but does not exist",
+		`class A { __add__(a, b) {} } A() + 1;`: "must have 1 parameters, but has 2",
+	}
+	for program, expected := range cases {
+		_, err := testSimpleProgram(program)
+		assert.ErrorContains(t, err, expected, program)
+	}
+}
+
+func TestForInErrors(t *testing.T) {
+	_, err := testSimpleProgram(`for (var x in 1) print x;`)
+	assert.ErrorContains(t, err, "can only loop over strings, lists, generators and objects with an 'iterator' method, but got '1'")
+
+	_, err = testSimpleProgram(`class A {} for (var x in A()) print x;`)
+	assert.ErrorContains(t, err, "Undefined property 'hasNext'")
+
+	_, err = testSimpleProgram(`class A { iterator() { return 1; } } for (var x in A()) print x;`)
+	assert.ErrorContains(t, err, "can't call 'hasNext' of '1' to loop over it, as it isn't an instance")
+}
+
+func TestTraits(t *testing.T) {
+	program := `
+trait Named {
+  name { return this.n; }
+  greet() { return "hi " + this.name; }
+}
+trait Loud {
+  shout() { return this.greet() + "!"; }
+  class make(n) { return this(n); }
+}
+trait Friendly with Named, Loud {}
+class Base {
+  init(n) { this.n = n; }
+  greet() { return "base"; }
+  shout() { return "base shout"; }
+}
+class Dog < Base with Friendly {
+  greet() { return "woof " + super.greet() + " " + this.name; }
+}
+class Cat with Named, Loud {
+  init(n) { this.n = n; }
+}
+print Dog("rex").shout();
+print Cat.make("tom").shout();
+print Friendly;`
+	testSimpleProgramWorksWithOutput(t, program, "woof base rex!\nhi tom!\n<trait Friendly>")
+}
+
+func TestTraitConflicts(t *testing.T) {
+	_, err := testSimpleProgram(`trait A { f() {} } trait B { f() {} } class C with A, B {}`)
+	assert.ErrorContains(t, err, "'C' includes conflicting methods 'f' from traits 'A' and 'B'")
+
+	// A class resolves a conflict by declaring the method itself
+	program := `
+trait A { f() { return "a"; } }
+trait B { f() { return "b"; } }
+class C with A, B { f() { return "c"; } }
+print C().f();`
+	testSimpleProgramWorksWithOutput(t, program, "c")
+
+	// The same method included through two traits isn't a conflict
+	program = `
+trait A { f() { return "a"; } }
+trait B with A {}
+class C with A, B {}
+print C().f();`
+	testSimpleProgramWorksWithOutput(t, program, "a")
+}
+
+func TestTraitErrors(t *testing.T) {
+	_, err := testSimpleProgram(`trait A { f() { return super.f(); } }`)
+	assert.ErrorContains(t, err, "'super' can't be used in a trait")
+
+	_, err = testSimpleProgram(`class A {} class B with A {}`)
+	assert.ErrorContains(t, err, "can only include traits with 'with', but 'A' is '<class A>'")
+
+	_, err = testSimpleProgram(`trait A with A {}`)
+	assert.ErrorContains(t, err, "'A' can't include itself as a trait")
+
+	_, err = testSimpleProgram(`trait A {} A();`)
+	assert.Error(t, err)
+}
+
+func TestGetterAndSetterErrors(t *testing.T) {
+	_, err := testSimpleProgram(`class A { set x() {} }`)
+	assert.ErrorContains(t, err, "exactly one parameter")
+
+	_, err = testSimpleProgram(`class A { class set x(v) {} }`)
+	assert.Error(t, err)
+
+	_, err = testSimpleProgram(`class A { init { return 1; } }`)
+	assert.Error(t, err)
+}
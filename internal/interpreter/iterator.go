@@ -0,0 +1,104 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/levpaul/glocks/internal/domain"
+	"github.com/levpaul/glocks/internal/parser"
+)
+
+// Names of the methods of the iterator protocol. An iterable object has an iterator() method, which returns an
+// iterator with hasNext() and next() methods. An object with hasNext() and next() but no iterator() is its own
+// iterator.
+const (
+	iteratorMethod = "iterator"
+	hasNextMethod  = "hasNext"
+	nextMethod     = "next"
+)
+
+// iterate returns a function which returns each value of an iterable in turn, reporting false once there are no
+// more. Strings are iterated by character, lists by element and generators by the values they yield.
+func (i *Interpreter) iterate(iterable domain.Value) (func() (domain.Value, bool, error), error) {
+	switch it := iterable.(type) {
+	case string:
+		chars := []rune(it)
+		idx := 0
+		return func() (domain.Value, bool, error) {
+			if idx >= len(chars) {
+				return nil, false, nil
+			}
+			idx++
+			if err := i.allocate(stringSize + 4); err != nil {
+				return nil, false, err
+			}
+			return string(chars[idx-1]), true, nil
+		}, nil
+	case *LoxList:
+		idx := 0
+		// The length is checked on each iteration, so elements pushed by the loop are also iterated over
+		return func() (domain.Value, bool, error) {
+			if idx >= len(it.elements) {
+				return nil, false, nil
+			}
+			idx++
+			return it.elements[idx-1], true, nil
+		}, nil
+	case *LoxGenerator:
+		return func() (domain.Value, bool, error) {
+			hasNext, err := i.generatorHasNext(it.generator)
+			if err != nil || !hasNext {
+				return nil, false, err
+			}
+			next, err := i.generatorNext(it.generator)
+			return next, err == nil, err
+		}, nil
+	case *LoxInstance:
+		return i.iterateInstance(it)
+	}
+	return nil, fmt.Errorf("can only loop over strings, lists, generators and objects with an '%s' method, but got '%v'",
+		iteratorMethod, iterable)
+}
+
+// iterateInstance iterates with the iterator protocol, using the iterator an instance returns from its
+// iterator() method, which may be a generator, or the instance itself if it has no such method
+func (i *Interpreter) iterateInstance(instance *LoxInstance) (func() (domain.Value, bool, error), error) {
+	iterator := domain.Value(instance)
+	if _, err := instance.klass.findMethod(iteratorMethod); err == nil {
+		var err error
+		if iterator, err = i.callMethod(instance, iteratorMethod); err != nil {
+			return nil, err
+		}
+		if g, isGenerator := iterator.(*LoxGenerator); isGenerator {
+			return i.iterate(g)
+		}
+	}
+
+	return func() (domain.Value, bool, error) {
+		hasNext, err := i.callMethod(iterator, hasNextMethod)
+		if err != nil || !isTruthy(hasNext) {
+			return nil, false, err
+		}
+		next, err := i.callMethod(iterator, nextMethod)
+		return next, err == nil, err
+	}, nil
+}
+
+// callMethod calls a method of an object which takes no arguments
+func (i *Interpreter) callMethod(object domain.Value, name string) (domain.Value, error) {
+	instance, isInstance := object.(*LoxInstance)
+	if !isInstance {
+		return nil, fmt.Errorf("can't call '%s' of '%v' to loop over it, as it isn't an instance", name, object)
+	}
+	method, err := instance.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	callable, isCallable := method.(parser.LoxCallable)
+	if !isCallable || !callable.Arity().Accepts(0) {
+		return nil, fmt.Errorf("'%s' of '%v' must be a method without parameters to loop over it", name, object)
+	}
+	if err = i.allocate(environmentSize + bindingSize + functionSize); err != nil {
+		return nil, err
+	}
+	return i.call(callable, nil)
+}
@@ -0,0 +1,47 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DefaultMaxCallDepth is the deepest Lox calls may nest before a stack overflow, unless WithMaxCallDepth is used.
+// It keeps runaway recursion well clear of exhausting the Go stack.
+const DefaultMaxCallDepth = 10000
+
+var (
+	// ErrStackOverflow is returned when Lox calls nest deeper than the maximum call depth
+	ErrStackOverflow = errors.New("stack overflow")
+	// ErrStepLimitExceeded is returned when a program executes more statements than its step budget allows
+	ErrStepLimitExceeded = errors.New("step limit exceeded")
+)
+
+// WithMaxSteps limits the number of statements executed by each call to Run or RunContext, where zero means
+// no limit. Every statement counts, including each statement of a block and each iteration of a loop body.
+func WithMaxSteps(n int) Option {
+	return func(i *Interpreter) {
+		i.maxSteps = n
+	}
+}
+
+// WithMaxCallDepth sets how deeply Lox calls may nest before failing with ErrStackOverflow
+func WithMaxCallDepth(n int) Option {
+	return func(i *Interpreter) {
+		i.maxCallDepth = n
+	}
+}
+
+// step counts a statement against the step budget, and aborts once the budget is spent or the context of
+// the run is cancelled
+func (i *Interpreter) step() error {
+	i.steps++
+	if i.maxSteps > 0 && i.steps > i.maxSteps {
+		return fmt.Errorf("%w: executed more than %d statements", ErrStepLimitExceeded, i.maxSteps)
+	}
+	select {
+	case <-i.ctx.Done():
+		return i.ctx.Err()
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,52 @@
+package interpreter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRunContextCancelsInfiniteLoop(t *testing.T) {
+	i := New(zap.NewNop().Sugar())
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := i.RunContext(ctx, `while (true) {}`)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	// The interpreter remains usable once the cancelled run has returned
+	require.NoError(t, i.Run(`var x = 1;`))
+}
+
+func TestMaxSteps(t *testing.T) {
+	i := New(zap.NewNop().Sugar(), WithMaxSteps(100))
+	err := i.Run(`var n = 0; while (true) { n = n + 1; }`)
+	require.ErrorIs(t, err, ErrStepLimitExceeded)
+
+	// The budget applies to each run separately
+	require.NoError(t, i.Run(`for (var n = 0; n < 10; n = n + 1) {}`))
+}
+
+func TestRecursionOverflowsStack(t *testing.T) {
+	i := New(zap.NewNop().Sugar())
+	// The recursive call isn't a tail call, so each call nests within the last
+	err := i.Run(`fun recurse(n) { return 1 + recurse(n + 1); } recurse(0);`)
+	require.ErrorIs(t, err, ErrStackOverflow)
+	var runtimeErr *RuntimeError
+	assert.ErrorAs(t, err, &runtimeErr)
+	assert.Equal(t, 0, i.CallDepth(), "expected the call stack to unwind after overflowing")
+}
+
+func TestMaxCallDepth(t *testing.T) {
+	program := `fun depth(n) { if (n > 0) return 1 + depth(n - 1); return 0; }`
+
+	i := New(zap.NewNop().Sugar(), WithMaxCallDepth(10))
+	require.NoError(t, i.Run(program+` depth(9);`))
+	require.ErrorIs(t, i.Run(program+` depth(10);`), ErrStackOverflow)
+}
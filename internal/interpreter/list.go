@@ -0,0 +1,90 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/levpaul/glocks/internal/domain"
+	"github.com/levpaul/glocks/internal/parser"
+)
+
+// LoxList is an ordered list of values, which grows as values are pushed onto it. Its elements are read and
+// written through its methods, as in list.get(0), and list.length is the number of elements it has.
+type LoxList struct {
+	elements []domain.Value
+}
+
+// newList allocates a list of the given elements
+func (i *Interpreter) newList(elements []domain.Value) (*LoxList, error) {
+	if err := i.allocate(listSize + int64(len(elements))*bindingSize); err != nil {
+		return nil, err
+	}
+	return &LoxList{elements: elements}, nil
+}
+
+func (l *LoxList) String() string {
+	return fmt.Sprintf("<list of %d>", len(l.elements))
+}
+
+// Get returns the length of the list, or one of its methods bound to the list
+func (l *LoxList) Get(name string) (domain.Value, error) {
+	switch name {
+	case "length":
+		return float64(len(l.elements)), nil
+	case "get":
+		return &native{name: "get", arity: parser.ExactArity(1), fn: func(i *Interpreter, args []domain.Value) (domain.Value, error) {
+			idx, err := l.index(args[0])
+			if err != nil {
+				return nil, err
+			}
+			return l.elements[idx], nil
+		}}, nil
+	case "set":
+		return &native{name: "set", arity: parser.ExactArity(2), fn: func(i *Interpreter, args []domain.Value) (domain.Value, error) {
+			idx, err := l.index(args[0])
+			if err != nil {
+				return nil, err
+			}
+			l.elements[idx] = args[1]
+			return nil, nil
+		}}, nil
+	case "push":
+		return &native{name: "push", arity: parser.ExactArity(1), fn: func(i *Interpreter, args []domain.Value) (domain.Value, error) {
+			if err := i.allocate(bindingSize); err != nil {
+				return nil, err
+			}
+			l.elements = append(l.elements, args[0])
+			return nil, nil
+		}}, nil
+	}
+	return nil, fmt.Errorf("Undefined property '%s' on list", name)
+}
+
+// index validates an index into the list, which must be a whole number within its bounds
+func (l *LoxList) index(v domain.Value) (int, error) {
+	n, isNum := v.(float64)
+	if !isNum || n != math.Trunc(n) || n < 0 || int(n) >= len(l.elements) {
+		return 0, fmt.Errorf("index '%v' is out of range for a list of length %d", v, len(l.elements))
+	}
+	return int(n), nil
+}
+
+// native is a function implemented by the interpreter, for natives which work with its own types such as
+// instances and lists
+type native struct {
+	name  string
+	arity parser.Arity
+	fn    func(i *Interpreter, args []domain.Value) (domain.Value, error)
+}
+
+func (n *native) Arity() parser.Arity {
+	return n.arity
+}
+
+func (n *native) Call(i parser.LoxInterpreter, args []domain.Value) (domain.Value, error) {
+	return n.fn(i.(*Interpreter), args)
+}
+
+func (n *native) String() string {
+	return fmt.Sprintf("<native fn %s>", n.name)
+}
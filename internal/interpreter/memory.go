@@ -0,0 +1,209 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/levpaul/glocks/internal/domain"
+	"github.com/levpaul/glocks/internal/environment"
+)
+
+// Approximate sizes in bytes of the values the interpreter allocates, including Go's own overheads. These are
+// only used for accounting, so they err on the side of being generous rather than exact.
+const (
+	environmentSize = 64
+	// bindingSize is the cost of a single entry in an environment or the fields of an instance
+	bindingSize  = 48
+	instanceSize = 64
+	stringSize   = 16
+	functionSize = 48
+	classSize    = 64
+	listSize     = 32
+	// generatorSize includes the stack of the goroutine a generator's body runs on
+	generatorSize = 8 * 1024
+
+	// minMeasureInterval is the least number of bytes allocated between measuring the memory in use
+	minMeasureInterval = 64 * 1024
+)
+
+// ErrMemoryLimitExceeded is returned when a program's memory use grows beyond the limit set by WithMemoryLimit
+var ErrMemoryLimitExceeded = domain.ErrMemoryLimitExceeded
+
+// WithMemoryLimit limits the approximate number of bytes a program may have in use at once, where zero means
+// no limit. Strings, instances, environments and functions count towards the limit.
+func WithMemoryLimit(bytes int64) Option {
+	return func(i *Interpreter) {
+		i.mem.limit = bytes
+	}
+}
+
+// memoryAccount tracks approximately how much memory a program is using. Every allocation is added to used,
+// but as the account doesn't know when values become unreachable, used is periodically corrected by measuring
+// the values reachable from the interpreter, much like a garbage collector's mark phase. Measurements are paced
+// so that their cost is proportional to the memory allocated.
+type memoryAccount struct {
+	limit int64
+	used  int64
+	// next is the value of used at which memory in use will next be measured
+	next int64
+	peak int64
+}
+
+// PeakMemory returns the most memory, in approximate bytes, in use during the last run
+func (i *Interpreter) PeakMemory() int64 {
+	return i.mem.peak
+}
+
+// MemoryInUse measures the memory, in approximate bytes, reachable from the interpreter's environments
+func (i *Interpreter) MemoryInUse() int64 {
+	m := &memoryMeasurement{visited: map[any]bool{}}
+	m.environment(i.globals)
+	m.environment(i.env)
+	for _, f := range i.frames {
+		m.environment(f.callerEnv)
+	}
+	return m.total
+}
+
+// resetMemory starts accounting for a new run, from the memory already in use by previous runs
+func (i *Interpreter) resetMemory() {
+	i.mem.peak = 0
+	i.mem.used = 0
+	i.measureMemory(0)
+}
+
+// allocate accounts for the given number of bytes about to be allocated, failing if it would exceed the limit
+func (i *Interpreter) allocate(bytes int64) error {
+	i.mem.used += bytes
+	if i.mem.used <= i.mem.next {
+		return nil
+	}
+	if i.measureMemory(bytes) {
+		return nil
+	}
+	return fmt.Errorf("%w: using %d bytes with a limit of %d", ErrMemoryLimitExceeded, i.mem.used, i.mem.limit)
+}
+
+// AllocateString accounts for a string of the given length which a native function is about to create
+func (i *Interpreter) AllocateString(length int) error {
+	return i.allocate(stringSize + int64(length))
+}
+
+// MemoryAvailable returns how many bytes of string a native function may create before exceeding the memory
+// limit, measuring the memory in use so that it is exact, and false when there is no limit
+func (i *Interpreter) MemoryAvailable() (int64, bool) {
+	if i.mem.limit <= 0 {
+		return 0, false
+	}
+	i.measureMemory(0)
+	available := i.mem.limit - i.mem.used - stringSize
+	if available < 0 {
+		available = 0
+	}
+	return available, true
+}
+
+// measureMemory corrects the memory in use with a measurement, plus the given bytes which are yet to be
+// allocated, and schedules the next measurement. It returns false if the memory in use exceeds the limit.
+func (i *Interpreter) measureMemory(pending int64) bool {
+	m := &i.mem
+	m.used = i.MemoryInUse() + pending
+	if m.used > m.peak {
+		m.peak = m.used
+	}
+
+	m.next = 2 * m.used
+	if m.next < m.used+minMeasureInterval {
+		m.next = m.used + minMeasureInterval
+	}
+	if m.limit > 0 && m.next > m.limit {
+		m.next = m.limit
+	}
+	return m.limit <= 0 || m.used <= m.limit
+}
+
+// memoryMeasurement totals the size of values, visiting each environment, instance and class only once
+type memoryMeasurement struct {
+	visited map[any]bool
+	total   int64
+}
+
+func (m *memoryMeasurement) environment(env *environment.Environment) {
+	for ; env != nil && !m.visited[env]; env = env.Enclosing {
+		m.visited[env] = true
+		m.total += environmentSize + int64(len(env.Values)+len(env.Slots))*bindingSize
+		for _, v := range env.Values {
+			m.value(v)
+		}
+		for _, s := range env.Slots {
+			m.value(s.Value)
+		}
+	}
+}
+
+func (m *memoryMeasurement) value(v domain.Value) {
+	switch val := v.(type) {
+	case string:
+		m.total += stringSize + int64(len(val))
+	case LoxFunction:
+		m.total += functionSize
+		m.environment(val.closure)
+	case *LoxClass:
+		if m.visited[val] {
+			return
+		}
+		m.visited[val] = true
+		m.total += classSize
+		m.methods(val.Methods, val.StaticMethods, val.Setters)
+		for _, t := range val.Traits {
+			m.value(t)
+		}
+		m.value(val.SuperClass)
+	case *LoxTrait:
+		if m.visited[val] {
+			return
+		}
+		m.visited[val] = true
+		m.total += classSize
+		m.methods(val.Methods, val.StaticMethods, val.Setters)
+		for _, t := range val.Traits {
+			m.value(t)
+		}
+	case *LoxGenerator:
+		if m.visited[val.generator] {
+			return
+		}
+		m.visited[val.generator] = true
+		m.total += generatorSize
+		m.environment(val.env)
+		m.value(val.next)
+	case *LoxList:
+		if m.visited[val] {
+			return
+		}
+		m.visited[val] = true
+		m.total += listSize + int64(len(val.elements))*bindingSize
+		for _, e := range val.elements {
+			m.value(e)
+		}
+	case *LoxInstance:
+		if m.visited[val] {
+			return
+		}
+		m.visited[val] = true
+		m.total += instanceSize + int64(len(val.fields))*bindingSize
+		for _, f := range val.fields {
+			m.value(f)
+		}
+		m.value(val.klass)
+	}
+}
+
+// methods totals the methods of a class or trait, of every kind, along with the environments they close over
+func (m *memoryMeasurement) methods(kinds ...map[string]LoxFunction) {
+	for _, methods := range kinds {
+		m.total += int64(len(methods)) * (bindingSize + functionSize)
+		for _, method := range methods {
+			m.environment(method.closure)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestMemoryLimitStringGrowth(t *testing.T) {
+	i := New(zap.NewNop().Sugar(), WithMemoryLimit(1<<20))
+	err := i.Run(`var s = "ab"; while (true) { s = s + s; }`)
+	require.ErrorIs(t, err, ErrMemoryLimitExceeded)
+	var runtimeErr *RuntimeError
+	assert.ErrorAs(t, err, &runtimeErr)
+}
+
+func TestMemoryLimitInstanceFields(t *testing.T) {
+	program := `class Bag {}
+var b = Bag();
+var key = "k";
+while (true) {
+  key = key + "k";
+  b.field = key;
+  var held = b;
+  b = Bag();
+  b.prev = held;
+}`
+	i := New(zap.NewNop().Sugar(), WithMemoryLimit(1<<20))
+	require.ErrorIs(t, i.Run(program), ErrMemoryLimitExceeded)
+}
+
+func TestMemoryLimitAllowsGarbage(t *testing.T) {
+	// Each string is discarded by the next iteration, so memory in use stays small even though far more than
+	// the limit is allocated overall
+	program := `for (var n = 0; n < 5000; n = n + 1) {
+  var s = "a string which is thrown away" + " after each iteration";
+}`
+	i := New(zap.NewNop().Sugar(), WithMemoryLimit(64*1024))
+	require.NoError(t, i.Run(program))
+	assert.Less(t, i.PeakMemory(), int64(64*1024))
+}
+
+func TestPeakMemory(t *testing.T) {
+	i := New(zap.NewNop().Sugar())
+	require.NoError(t, i.Run(`var s = "0123456789"; for (var n = 0; n < 14; n = n + 1) { s = s + s; } s = nil;`))
+
+	// s peaks at 10 * 2^14 bytes before being released
+	assert.GreaterOrEqual(t, i.PeakMemory(), int64(10<<14))
+	assert.Less(t, i.MemoryInUse(), int64(10<<14))
+}
+
+func TestMemoryInUseCountsEveryKindOfMethod(t *testing.T) {
+	// big is only reachable through the closure of a method of the class kept in c
+	declarations := []string{
+		`class A { class get() { return big; } }`,
+		`class A { set value(v) { big = v; } }`,
+		`class A { get { return big; } }`,
+		`trait T { class get() { return big; } } class A with T {}`,
+	}
+	for _, decl := range declarations {
+		i := New(zap.NewNop().Sugar())
+		program := `var c;
+{
+  var big = "0123456789";
+  for (var n = 0; n < 14; n = n + 1) { big = big + big; }
+  ` + decl + `
+  c = A;
+}`
+		require.NoError(t, i.Run(program), decl)
+		assert.GreaterOrEqual(t, i.MemoryInUse(), int64(10<<14), decl)
+	}
+}
@@ -0,0 +1,116 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/levpaul/glocks/internal/domain"
+	"github.com/levpaul/glocks/internal/lexer"
+)
+
+// operatorMethods are the names of the methods a class declares to overload each binary operator for its
+// instances, which are called on the left operand with the right operand as their argument. != is the negation of
+// __eq__.
+var operatorMethods = map[lexer.TokenType]string{
+	lexer.PLUS:          "__add__",
+	lexer.MINUS:         "__sub__",
+	lexer.STAR:          "__mul__",
+	lexer.SLASH:         "__div__",
+	lexer.LESS:          "__lt__",
+	lexer.LESS_EQUAL:    "__le__",
+	lexer.GREATER:       "__gt__",
+	lexer.GREATER_EQUAL: "__ge__",
+	lexer.EQUAL_EQUAL:   "__eq__",
+	lexer.BANG_EQUAL:    "__eq__",
+}
+
+// reflectedMethods are the names of the methods called on the right operand of a binary operator, with the left
+// operand as their argument, when the left operand doesn't overload it. Arithmetic has methods of its own, such as
+// __radd__, while comparisons use their mirror image, so 1 < v calls v.__gt__(1).
+var reflectedMethods = map[lexer.TokenType]string{
+	lexer.PLUS:          "__radd__",
+	lexer.MINUS:         "__rsub__",
+	lexer.STAR:          "__rmul__",
+	lexer.SLASH:         "__rdiv__",
+	lexer.LESS:          "__gt__",
+	lexer.LESS_EQUAL:    "__ge__",
+	lexer.GREATER:       "__lt__",
+	lexer.GREATER_EQUAL: "__le__",
+	lexer.EQUAL_EQUAL:   "__eq__",
+	lexer.BANG_EQUAL:    "__eq__",
+}
+
+// negateMethod is the name of the method overloading unary minus, which is called with no arguments
+const negateMethod = "__neg__"
+
+// binaryOverload runs the method overloading a binary operator, trying the left operand's method before the right
+// operand's reflected one. It reports false when neither operand is an instance, or for equality when neither
+// overloads it, which then falls back to comparing identity. Any other operator with an instance operand which
+// doesn't overload it fails, as it isn't defined for instances.
+func (i *Interpreter) binaryOverload(op *lexer.Token, left, right domain.Value) (domain.Value, bool, error) {
+	name, overloadable := operatorMethods[op.Type]
+	if !overloadable {
+		return nil, false, nil
+	}
+	leftInstance, leftIsInstance := left.(*LoxInstance)
+	rightInstance, rightIsInstance := right.(*LoxInstance)
+
+	var result domain.Value
+	var err error
+	if method, findErr := findOperator(leftInstance, name); findErr == nil {
+		result, err = i.callOperator(method, leftInstance, []domain.Value{right})
+	} else if method, findErr := findOperator(rightInstance, reflectedMethods[op.Type]); findErr == nil {
+		result, err = i.callOperator(method, rightInstance, []domain.Value{left})
+	} else {
+		switch {
+		case op.Type == lexer.EQUAL_EQUAL || op.Type == lexer.BANG_EQUAL:
+		case leftIsInstance:
+			err = fmt.Errorf("operator '%s' is undefined for '%v', as class '%s' has no method '%s'",
+				op.Lexeme, left, leftInstance.klass.Name, name)
+		case rightIsInstance:
+			err = fmt.Errorf("operator '%s' is undefined for '%v' on its right, as class '%s' has no method '%s'",
+				op.Lexeme, right, rightInstance.klass.Name, reflectedMethods[op.Type])
+		}
+		return nil, false, err
+	}
+	if err != nil {
+		return nil, true, err
+	}
+	switch op.Type {
+	case lexer.EQUAL_EQUAL:
+		return isTruthy(result), true, nil
+	case lexer.BANG_EQUAL:
+		return !isTruthy(result), true, nil
+	}
+	return result, true, nil
+}
+
+// findOperator finds the method overloading an operator on an instance, which fails when it is nil
+func findOperator(instance *LoxInstance, name string) (LoxFunction, error) {
+	if instance == nil {
+		return LoxFunction{}, fmt.Errorf("operands which aren't instances have no method '%s'", name)
+	}
+	return instance.klass.findMethod(name)
+}
+
+// negateOverload runs the method an instance overloads unary minus with
+func (i *Interpreter) negateOverload(operand *LoxInstance) (domain.Value, error) {
+	method, err := operand.klass.findMethod(negateMethod)
+	if err != nil {
+		return nil, fmt.Errorf("operator '-' is undefined for '%v', as class '%s' has no method '%s'",
+			operand, operand.klass.Name, negateMethod)
+	}
+	return i.callOperator(method, operand, nil)
+}
+
+// callOperator calls an operator method bound to the instance it was found on
+func (i *Interpreter) callOperator(method LoxFunction, instance *LoxInstance, args []domain.Value) (domain.Value, error) {
+	if !method.Arity().Accepts(len(args)) {
+		return nil, fmt.Errorf("operator method '%s' of class '%s' must have %d parameters, but has %s",
+			method.declaration.Name, instance.klass.Name, len(args), method.Arity())
+	}
+	// Like any other method, it is bound to the instance with an environment holding 'this'
+	if err := i.allocate(environmentSize + bindingSize + functionSize); err != nil {
+		return nil, err
+	}
+	return i.call(method.Bind(instance), args)
+}
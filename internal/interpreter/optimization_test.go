@@ -0,0 +1,44 @@
+package interpreter
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func runWithOutput(program string, opts ...Option) (string, error) {
+	out := &bytes.Buffer{}
+	err := New(zap.NewNop().Sugar(), append([]Option{WithStdout(out)}, opts...)...).Run(program)
+	return out.String(), err
+}
+
+// TestOptimizationKeepsBehaviour runs programs with and without the optimizer, which must print the same output
+// and fail with the same errors
+func TestOptimizationKeepsBehaviour(t *testing.T) {
+	programs := []string{
+		`print 1 + 2 * 3 - 4 / 2; print "a" + "b" + "c"; print -(2 + 3); print !(1 >= 2);`,
+		`print nil or "default"; print 1 and 2; print false and undefined; print true or undefined;`,
+		`print 1 == 1; print "a" != "a"; print nil == false; print 1 / 0;`,
+		`var x = 1; if (1 > 2) { print "never"; } else { print x; } if (true) print "always";`,
+		`var n = 0; while (false) { n = n + 1; } for (var i = 0; i < 3; i = i + 1) print i; print n;`,
+		`fun f(x) { return x * 2; print "unreachable"; } print f(2 + 3);`,
+		`fun f() { { var a = 1; return a; } print "unreachable"; } print f();`,
+		`class A { m() { if (false) return 0; return 1 + 1; } } print A().m();`,
+		`print "before"; print "a" + 1;`,
+		`print "before"; print -"a";`,
+		`if (false) { print "a" + 1; } print "not evaluated";`,
+		`print 1 < "a";`,
+	}
+	for _, program := range programs {
+		expectedOut, expectedErr := runWithOutput(program)
+		out, err := runWithOutput(program, WithOptimization())
+		assert.Equal(t, expectedOut, out, "output of: %s", program)
+		if expectedErr == nil {
+			assert.NoError(t, err, "running: %s", program)
+		} else {
+			assert.EqualError(t, err, expectedErr.Error(), "running: %s", program)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package interpreter
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/levpaul/glocks/internal/domain"
+	"github.com/levpaul/glocks/internal/parser"
+)
+
+// interpreterNatives are the natives implemented by the interpreter, which inspect and create values of its own
+// types. Like natives without a capability, they can't reach outside of the script and so are always defined.
+var interpreterNatives = []*native{
+	{name: "type", arity: parser.ExactArity(1), fn: typeOf},
+	{name: "list", arity: parser.Arity{Max: parser.Variadic}, fn: func(i *Interpreter, args []domain.Value) (domain.Value, error) {
+		return i.newList(append([]domain.Value(nil), args...))
+	}},
+	{name: "methods", arity: parser.ExactArity(1), fn: methodNames},
+	{name: "fields", arity: parser.ExactArity(1), fn: fieldNames},
+	{name: "hasField", arity: parser.ExactArity(2), fn: hasField},
+	{name: "getField", arity: parser.ExactArity(2), fn: getField},
+	{name: "setField", arity: parser.ExactArity(3), fn: setField},
+}
+
+// typeOf returns the name of the type of a value
+func typeOf(i *Interpreter, args []domain.Value) (domain.Value, error) {
+	switch args[0].(type) {
+	case nil:
+		return "nil", nil
+	case bool:
+		return "bool", nil
+	case float64:
+		return "number", nil
+	case string:
+		return "string", nil
+	case *LoxClass:
+		return "class", nil
+	case *LoxTrait:
+		return "trait", nil
+	case *LoxInstance:
+		return "instance", nil
+	case *LoxList:
+		return "list", nil
+	case *LoxGenerator:
+		return "generator", nil
+	case parser.LoxCallable:
+		return "function", nil
+	}
+	return nil, fmt.Errorf("type of '%v' is unknown", args[0])
+}
+
+// methodNames returns a sorted list of the names of the methods of a class, or of the class of an instance,
+// including those it inherits. Instance methods, getters and setters are listed for both, along with class methods
+// when given a class, as they can't be called on its instances.
+func methodNames(i *Interpreter, args []domain.Value) (domain.Value, error) {
+	klass, isClass := args[0].(*LoxClass)
+	ofClass := isClass
+	if instance, isInstance := args[0].(*LoxInstance); isInstance {
+		klass, isClass = instance.klass, true
+	}
+	if !isClass {
+		return nil, fmt.Errorf("methods expects a class or instance, but got '%v'", args[0])
+	}
+
+	seen := map[string]bool{}
+	for c := klass; c != nil; c, _ = c.SuperClass.(*LoxClass) {
+		kinds := []map[string]LoxFunction{c.Methods, c.Setters}
+		if ofClass {
+			kinds = append(kinds, c.StaticMethods)
+		}
+		for _, methods := range kinds {
+			for name := range methods {
+				seen[name] = true
+			}
+		}
+	}
+	return i.sortedNames(seen)
+}
+
+// fieldNames returns a sorted list of the names of the fields of an instance
+func fieldNames(i *Interpreter, args []domain.Value) (domain.Value, error) {
+	instance, err := instanceArg("fields", args[0])
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]bool{}
+	for name := range instance.fields {
+		names[name] = true
+	}
+	return i.sortedNames(names)
+}
+
+// hasField reports whether an instance has a field with the given name, ignoring its methods
+func hasField(i *Interpreter, args []domain.Value) (domain.Value, error) {
+	instance, name, err := fieldArgs("hasField", args)
+	if err != nil {
+		return nil, err
+	}
+	_, exists := instance.fields[name]
+	return exists, nil
+}
+
+// getField returns the field of an instance with the given name, ignoring its methods
+func getField(i *Interpreter, args []domain.Value) (domain.Value, error) {
+	instance, name, err := fieldArgs("getField", args)
+	if err != nil {
+		return nil, err
+	}
+	val, exists := instance.fields[name]
+	if !exists {
+		return nil, fmt.Errorf("Undefined field '%s' on instance of class '%s'", name, instance.klass.Name)
+	}
+	return val, nil
+}
+
+// setField sets the field of an instance with the given name, without running any setter
+func setField(i *Interpreter, args []domain.Value) (domain.Value, error) {
+	instance, name, err := fieldArgs("setField", args)
+	if err != nil {
+		return nil, err
+	}
+	if _, exists := instance.fields[name]; !exists {
+		if err := i.allocate(bindingSize); err != nil {
+			return nil, err
+		}
+	}
+	instance.Set(name, args[2])
+	return nil, nil
+}
+
+func instanceArg(fn string, v domain.Value) (*LoxInstance, error) {
+	instance, isInstance := v.(*LoxInstance)
+	if !isInstance {
+		return nil, fmt.Errorf("%s expects an instance, but got '%v'", fn, v)
+	}
+	return instance, nil
+}
+
+// fieldArgs validates the instance and field name which are the first arguments of a field native
+func fieldArgs(fn string, args []domain.Value) (*LoxInstance, string, error) {
+	instance, err := instanceArg(fn, args[0])
+	if err != nil {
+		return nil, "", err
+	}
+	name, isString := args[1].(string)
+	if !isString {
+		return nil, "", fmt.Errorf("%s expects a string field name, but got '%v'", fn, args[1])
+	}
+	return instance, name, nil
+}
+
+// sortedNames returns a list of names in sorted order
+func (i *Interpreter) sortedNames(names map[string]bool) (*LoxList, error) {
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	elements := make([]domain.Value, len(sorted))
+	for idx, name := range sorted {
+		elements[idx] = name
+	}
+	return i.newList(elements)
+}
@@ -0,0 +1,116 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/levpaul/glocks/internal/domain"
+)
+
+// toStringMethod is the name of the method a class declares to choose how its instances are shown
+const toStringMethod = "toString"
+
+// Stringify returns the text a value is printed as. Instances of classes with a toString method are shown as the
+// string it returns, other than within their own toString, where they are shown as the default "Name instance" to
+// avoid recursing forever. Lists are shown as their elements within brackets.
+func (i *Interpreter) Stringify(v domain.Value) (string, error) {
+	switch val := v.(type) {
+	case nil:
+		return "nil", nil
+	case float64:
+		return formatNumber(val), nil
+	case *LoxInstance:
+		return i.stringifyInstance(val)
+	case *LoxList:
+		return i.stringifyList(val)
+	}
+	return fmt.Sprint(v), nil
+}
+
+func (i *Interpreter) stringifyInstance(instance *LoxInstance) (string, error) {
+	method, err := instance.klass.findMethod(toStringMethod)
+	if err != nil || i.stringifying[instance] {
+		return instance.String(), nil
+	}
+	if !method.Arity().Accepts(0) {
+		return "", fmt.Errorf("method '%s' of class '%s' must have no parameters, but has %s",
+			toStringMethod, instance.klass.Name, method.Arity())
+	}
+
+	i.markStringifying(instance)
+	defer delete(i.stringifying, instance)
+
+	result, err := i.call(method.Bind(instance), nil)
+	if err != nil {
+		return "", err
+	}
+	text, isString := result.(string)
+	if !isString {
+		return "", fmt.Errorf("method '%s' of class '%s' must return a string, but returned '%v'",
+			toStringMethod, instance.klass.Name, result)
+	}
+	return text, nil
+}
+
+func (i *Interpreter) stringifyList(list *LoxList) (string, error) {
+	if i.stringifying[list] {
+		return "[...]", nil
+	}
+	i.markStringifying(list)
+	defer delete(i.stringifying, list)
+
+	builder := strings.Builder{}
+	builder.WriteString("[")
+	for idx, e := range list.elements {
+		if idx > 0 {
+			builder.WriteString(", ")
+		}
+		text, err := i.Stringify(e)
+		if err != nil {
+			return "", err
+		}
+		builder.WriteString(text)
+	}
+	builder.WriteString("]")
+	return builder.String(), nil
+}
+
+// markStringifying records that a value is being stringified, until it is deleted from i.stringifying
+func (i *Interpreter) markStringifying(v any) {
+	if i.stringifying == nil {
+		i.stringifying = map[any]bool{}
+	}
+	i.stringifying[v] = true
+}
+
+// formatNumber formats a number with as few digits as represent it exactly. Whole numbers below 1e21 are written
+// out in full rather than with an exponent, as JavaScript does.
+func formatNumber(n float64) string {
+	switch {
+	case math.IsNaN(n):
+		return "nan"
+	case math.IsInf(n, 1):
+		return "inf"
+	case math.IsInf(n, -1):
+		return "-inf"
+	case n == math.Trunc(n) && math.Abs(n) < 1e21:
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(n, 'g', -1, 64)
+}
+
+// printResult prints the value of an expression statement in the REPL, doing nothing outside of it or for
+// statements which don't evaluate to a value
+func (i *Interpreter) printResult(result domain.Value) error {
+	if !i.replMode || result == nil {
+		return nil
+	}
+	text, err := i.Stringify(result)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(i.output(), "evaluates to:", text)
+	return nil
+}
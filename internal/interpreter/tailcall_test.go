@@ -0,0 +1,83 @@
+package interpreter
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/levpaul/glocks/internal/domain"
+	"github.com/levpaul/glocks/internal/lexer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTailCallSelfRecursion(t *testing.T) {
+	// Far deeper than DefaultMaxCallDepth, which only tail calls can reach
+	out, err := runWithOutput(`
+fun count(n, acc) {
+  if (n == 0) return acc;
+  return count(n - 1, acc + 1);
+}
+print count(100000, 0);`)
+	require.NoError(t, err)
+	assert.Equal(t, "100000\n", out)
+}
+
+func TestTailCallMutualRecursion(t *testing.T) {
+	out, err := runWithOutput(`
+fun isEven(n) { if (n == 0) return true; return (isOdd(n - 1)); }
+fun isOdd(n) { if (n == 0) return false; return isEven(n - 1); }
+print isEven(50000);
+print isOdd(50001);`)
+	require.NoError(t, err)
+	assert.Equal(t, "true\ntrue\n", out)
+}
+
+func TestTailCallMethods(t *testing.T) {
+	out, err := runWithOutput(`
+class Walker {
+  init(steps) { this.steps = steps; }
+  walk(n) {
+    if (n == this.steps) return n;
+    return this.walk(n + 1);
+  }
+}
+print Walker(20000).walk(0);`)
+	require.NoError(t, err)
+	assert.Equal(t, "20000\n", out)
+}
+
+func TestCallsNotInTailPosition(t *testing.T) {
+	out, err := runWithOutput(`
+class A { init(v) { this.v = v; } }
+fun sum(n) { if (n == 0) return 0; return n + sum(n - 1); }
+fun make(v) { return A(v); }
+fun started() { return clock() >= 0; }
+print sum(100);
+print make(3).v;
+print started();`)
+	require.NoError(t, err)
+	assert.Equal(t, "5050\n3\ntrue\n", out)
+}
+
+// recordingCallHook records the calls it is notified of, in order
+type recordingCallHook struct {
+	events []string
+}
+
+func (r *recordingCallHook) EnterCall(name string, _ lexer.Position, args []domain.Value) {
+	r.events = append(r.events, fmt.Sprintf("enter %s%v", name, args))
+}
+
+func (r *recordingCallHook) ExitCall(result domain.Value, err error) {
+	r.events = append(r.events, fmt.Sprintf("exit %v", result))
+}
+
+func TestTailCallHooks(t *testing.T) {
+	hook := &recordingCallHook{}
+	_, err := runWithOutput(`fun down(n) { if (n == 0) return "done"; return down(n - 1); } down(2);`, WithCallHook(hook))
+	require.NoError(t, err)
+
+	// Each tail call is still seen as nested within the call which made it, returning the same result
+	expected := []string{"enter down[2]", "enter down[1]", "enter down[0]", "exit done", "exit done", "exit done"}
+	assert.Equal(t, expected, hook.events)
+}
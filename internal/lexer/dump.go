@@ -0,0 +1,79 @@
+package lexer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var escapeNewlines = strings.NewReplacer("\n", `\n`, "\r", `\r`)
+
+// WriteTokenTable writes a table of tokens, one per line, with the position, type, lexeme and literal of each
+func WriteTokenTable(w io.Writer, tokens []*Token) error {
+	rows := [][]string{{"POSITION", "TYPE", "LEXEME", "LITERAL"}}
+	for _, t := range tokens {
+		// Multi-line strings are escaped so each token stays on a single line
+		rows = append(rows, []string{t.Position().String(), t.Type.String(), escapeNewlines.Replace(t.Lexeme),
+			escapeNewlines.Replace(formatLiteral(t.Literal))})
+	}
+
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for col, cell := range row {
+			if len(cell) > widths[col] {
+				widths[col] = len(cell)
+			}
+		}
+	}
+
+	b := &strings.Builder{}
+	for _, row := range rows {
+		line := ""
+		for col, cell := range row {
+			line += fmt.Sprintf("%-*s  ", widths[col], cell)
+		}
+		b.WriteString(strings.TrimRight(line, " "))
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// formatLiteral formats the literal value of a token, quoting strings so they can be told apart from numbers
+func formatLiteral(literal any) string {
+	switch l := literal.(type) {
+	case nil:
+		return ""
+	case string:
+		return fmt.Sprintf("%q", l)
+	}
+	return fmt.Sprint(literal)
+}
+
+// tokenJSON is the JSON form of a token
+type tokenJSON struct {
+	Type    string `json:"type"`
+	Lexeme  string `json:"lexeme"`
+	Literal any    `json:"literal"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+}
+
+// MarshalTokensJSON serialises tokens as a JSON array, where each token is an object with its type name, lexeme,
+// literal value (null for tokens without one), line and column
+func MarshalTokensJSON(tokens []*Token) ([]byte, error) {
+	out := make([]tokenJSON, len(tokens))
+	for idx, t := range tokens {
+		out[idx] = tokenJSON{Type: t.Type.String(), Lexeme: t.Lexeme, Literal: t.Literal, Line: t.Line, Column: t.Column}
+	}
+	// Lexemes like '<' are left as they are, rather than escaped for embedding in HTML
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(out); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
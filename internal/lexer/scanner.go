@@ -3,28 +3,33 @@ package lexer
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"go.uber.org/zap"
 )
 
 // keywordMap maps reserved keywords to their respective TokenType
 var keywordMap = map[string]TokenType{
-	"and":    AND,
-	"class":  CLASS,
-	"else":   ELSE,
-	"false":  FALSE,
-	"fun":    FUN,
-	"for":    FOR,
-	"if":     IF,
-	"nil":    NIL,
-	"or":     OR,
-	"print":  PRINT,
-	"return": RETURN,
-	"super":  SUPER,
-	"this":   THIS,
-	"true":   TRUE,
-	"var":    VAR,
-	"while":  WHILE,
+	"and":        AND,
+	"class":      CLASS,
+	"else":       ELSE,
+	"false":      FALSE,
+	"fun":        FUN,
+	"for":        FOR,
+	"if":         IF,
+	"instanceof": INSTANCEOF,
+	"nil":        NIL,
+	"or":         OR,
+	"print":      PRINT,
+	"return":     RETURN,
+	"super":      SUPER,
+	"this":       THIS,
+	"trait":      TRAIT,
+	"true":       TRUE,
+	"var":        VAR,
+	"while":      WHILE,
+	"with":       WITH,
+	"yield":      YIELD,
 }
 
 // Scanner is responsible for scanning source code and converting it into tokens
@@ -32,10 +37,15 @@ type Scanner struct {
 	source string
 	log    *zap.SugaredLogger
 	tokens []*Token
+	errors []*PositionError
 
 	start   int
 	current int
 	line    int
+	// lineStart is the index in source of the first character of the current line
+	lineStart int
+	// column is the column of the token currently being scanned
+	column int
 }
 
 // NewScanner returns a new instance of Scanner
@@ -53,8 +63,13 @@ func (s *Scanner) ScanTokens() []*Token {
 	for !s.isAtEnd() {
 		// Reset start of current token being parsed
 		s.start = s.current
+		s.column = s.current - s.lineStart + 1
 		if err := s.scanToken(); err != nil {
 			s.log.With("error", err).Errorf("Failed to scan token at line %d\n", s.line)
+			s.errors = append(s.errors, &PositionError{
+				Pos: Position{Line: s.line, Column: s.column},
+				Err: err,
+			})
 		}
 	}
 
@@ -63,11 +78,18 @@ func (s *Scanner) ScanTokens() []*Token {
 		Lexeme:  "",
 		Literal: nil,
 		Line:    s.line,
+		Column:  s.current - s.lineStart + 1,
 	})
 
 	return s.tokens
 }
 
+// Errors returns all errors found during the last call to ScanTokens, invalid characters are skipped over
+// rather than stopping the scan
+func (s *Scanner) Errors() []*PositionError {
+	return s.errors
+}
+
 // scanToken reads the next token from the source and adds it to the tokens slice
 // in the scanner. It returns an error if the token is invalid.
 func (s *Scanner) scanToken() error {
@@ -84,7 +106,12 @@ func (s *Scanner) scanToken() error {
 	case ',':
 		s.addToken(COMMA)
 	case '.':
-		s.addToken(DOT)
+		if strings.HasPrefix(s.source[s.current:], "..") {
+			s.current += 2
+			s.addToken(ELLIPSIS)
+		} else {
+			s.addToken(DOT)
+		}
 	case '-':
 		s.addToken(MINUS)
 	case '+':
@@ -93,6 +120,8 @@ func (s *Scanner) scanToken() error {
 		s.addToken(SEMICOLON)
 	case '*':
 		s.addToken(STAR)
+	case ':':
+		s.addToken(COLON)
 	case '!':
 		s.addToken(s.matchTern('=', BANG_EQUAL, BANG))
 	case '=':
@@ -116,6 +145,7 @@ func (s *Scanner) scanToken() error {
 		//  === ignoring whitespace ===
 	case '\n':
 		s.line++
+		s.lineStart = s.current
 	case '"':
 		s.scanString()
 	default:
@@ -137,7 +167,7 @@ func (s *Scanner) scanToken() error {
 // scanIdentifier scans an identifier token from the source and adds it to the tokens slice
 // in the scanner.
 func (s *Scanner) scanIdentifier() {
-	for isAlpha(s.peek()) { // scan through initial digits
+	for IsIdentifierChar(s.peek()) {
 		s.advance()
 	}
 	identifier := s.source[s.start:s.current]
@@ -180,6 +210,7 @@ func (s *Scanner) scanString() {
 	for s.peek() != '"' && !s.isAtEnd() {
 		if s.peek() == '\n' {
 			s.line++
+			s.lineStart = s.current + 1
 		}
 		s.advance()
 	}
@@ -232,6 +263,7 @@ func (s *Scanner) addLiteralToken(t TokenType, lit any) {
 		Lexeme:  s.source[s.start:s.current],
 		Literal: lit,
 		Line:    s.line,
+		Column:  s.column,
 	})
 }
 
@@ -247,9 +279,14 @@ func (s *Scanner) isAtEnd() bool {
 }
 
 func isAlpha(r rune) bool {
-	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r == '_'
 }
 
 func isDigit(r rune) bool {
 	return r >= '0' && r <= '9'
 }
+
+// IsIdentifierChar reports whether a character can be part of an identifier after its first character
+func IsIdentifierChar(r rune) bool {
+	return isAlpha(r) || isDigit(r)
+}
@@ -0,0 +1,30 @@
+package lexer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestScanIdentifiers(t *testing.T) {
+	s := NewScanner("_a x1 __add__ or_else 2x", zap.NewNop().Sugar())
+	tokens := s.ScanTokens()
+	require.Empty(t, s.Errors())
+
+	var scanned []string
+	for _, tok := range tokens {
+		scanned = append(scanned, tok.Type.String()+" "+tok.Lexeme)
+	}
+	// Identifiers may contain underscores and digits, but not start with a digit
+	assert.Equal(t, []string{
+		"IDENTIFIER _a",
+		"IDENTIFIER x1",
+		"IDENTIFIER __add__",
+		"IDENTIFIER or_else",
+		"NUMBER 2",
+		"IDENTIFIER x",
+		"EOF ",
+	}, scanned)
+}
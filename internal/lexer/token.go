@@ -16,6 +16,7 @@ const (
 	SEMICOLON
 	SLASH
 	STAR
+	COLON
 
 	// One or two character tokens.
 	BANG
@@ -26,6 +27,8 @@ const (
 	GREATER_EQUAL
 	LESS
 	LESS_EQUAL
+	// ELLIPSIS is '...', which marks a rest parameter
+	ELLIPSIS
 
 	// Literals.
 	IDENTIFIER
@@ -40,28 +43,124 @@ const (
 	FUN
 	FOR
 	IF
+	INSTANCEOF
 	NIL
 	OR
 	PRINT
 	RETURN
 	SUPER
 	THIS
+	TRAIT
 	TRUE
 	VAR
 	WHILE
+	WITH
+	YIELD
 
 	EOF
 )
 
+// tokenTypeNames are the names of each TokenType, as written in the const list above
+var tokenTypeNames = [...]string{
+	LEFT_PAREN:  "LEFT_PAREN",
+	RIGHT_PAREN: "RIGHT_PAREN",
+	LEFT_BRACE:  "LEFT_BRACE",
+	RIGHT_BRACE: "RIGHT_BRACE",
+	COMMA:       "COMMA",
+	DOT:         "DOT",
+	MINUS:       "MINUS",
+	PLUS:        "PLUS",
+	SEMICOLON:   "SEMICOLON",
+	SLASH:       "SLASH",
+	STAR:        "STAR",
+	COLON:       "COLON",
+
+	BANG:          "BANG",
+	BANG_EQUAL:    "BANG_EQUAL",
+	EQUAL:         "EQUAL",
+	EQUAL_EQUAL:   "EQUAL_EQUAL",
+	GREATER:       "GREATER",
+	GREATER_EQUAL: "GREATER_EQUAL",
+	LESS:          "LESS",
+	LESS_EQUAL:    "LESS_EQUAL",
+	ELLIPSIS:      "ELLIPSIS",
+
+	IDENTIFIER: "IDENTIFIER",
+	STRING:     "STRING",
+	NUMBER:     "NUMBER",
+
+	AND:        "AND",
+	CLASS:      "CLASS",
+	ELSE:       "ELSE",
+	FALSE:      "FALSE",
+	FUN:        "FUN",
+	FOR:        "FOR",
+	IF:         "IF",
+	INSTANCEOF: "INSTANCEOF",
+	NIL:        "NIL",
+	OR:         "OR",
+	PRINT:      "PRINT",
+	RETURN:     "RETURN",
+	SUPER:      "SUPER",
+	THIS:       "THIS",
+	TRAIT:      "TRAIT",
+	TRUE:       "TRUE",
+	VAR:        "VAR",
+	WHILE:      "WHILE",
+	WITH:       "WITH",
+	YIELD:      "YIELD",
+
+	EOF: "EOF",
+}
+
+// String returns the name of the token type, such as "LEFT_PAREN"
+func (t TokenType) String() string {
+	if t < 0 || int(t) >= len(tokenTypeNames) {
+		return fmt.Sprintf("TokenType(%d)", int(t))
+	}
+	return tokenTypeNames[t]
+}
+
 type Token struct {
 	Type    TokenType
 	Lexeme  string
 	Literal any
 	Line    int
+	Column  int
+}
+
+// Position is a location in Lox source code. Both Line and Column start counting from 1, a zero
+// Position means the location is unknown.
+type Position struct {
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// PositionError is an error which occurred at a known position in the source
+type PositionError struct {
+	Pos Position
+	Err error
+}
+
+func (e *PositionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PositionError) Unwrap() error {
+	return e.Err
 }
 
 func (t *Token) String() string {
-	return fmt.Sprintf("Type:%d, Lexeme:%s, Line:%d", t.Type, t.Lexeme, t.Line)
+	return fmt.Sprintf("Type:%s, Lexeme:%s, Line:%d", t.Type, t.Lexeme, t.Line)
+}
+
+// Position returns the location of the first character of the token
+func (t *Token) Position() Position {
+	return Position{Line: t.Line, Column: t.Column}
 }
 
 func (t *Token) GenerateTokenError(msg string) error {
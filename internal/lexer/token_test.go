@@ -0,0 +1,55 @@
+package lexer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTokenTypeString(t *testing.T) {
+	assert.Equal(t, "LEFT_PAREN", LEFT_PAREN.String())
+	assert.Equal(t, "LESS_EQUAL", LESS_EQUAL.String())
+	assert.Equal(t, "WHILE", WHILE.String())
+	assert.Equal(t, "EOF", EOF.String())
+	assert.Equal(t, "TokenType(99)", TokenType(99).String())
+
+	// Every type up to EOF must have a name
+	for tt := LEFT_PAREN; tt <= EOF; tt++ {
+		assert.NotEmpty(t, tokenTypeNames[tt], "TokenType %d has no name", int(tt))
+	}
+}
+
+func TestWriteTokenTable(t *testing.T) {
+	out := &bytes.Buffer{}
+	require.NoError(t, WriteTokenTable(out, NewScanner(`var s = "hi";`+"\nx<=1;", zap.NewNop().Sugar()).ScanTokens()))
+
+	expected := `POSITION  TYPE        LEXEME  LITERAL
+1:1       VAR         var
+1:5       IDENTIFIER  s
+1:7       EQUAL       =
+1:9       STRING      "hi"    "hi"
+1:13      SEMICOLON   ;
+2:1       IDENTIFIER  x
+2:2       LESS_EQUAL  <=
+2:4       NUMBER      1       1
+2:5       SEMICOLON   ;
+2:6       EOF
+`
+	assert.Equal(t, expected, out.String())
+}
+
+func TestMarshalTokensJSON(t *testing.T) {
+	out, err := MarshalTokensJSON(NewScanner(`print 2.5<a;`, zap.NewNop().Sugar()).ScanTokens())
+	require.NoError(t, err)
+
+	expected := `[{"type":"PRINT","lexeme":"print","literal":null,"line":1,"column":1},` +
+		`{"type":"NUMBER","lexeme":"2.5","literal":2.5,"line":1,"column":7},` +
+		`{"type":"LESS","lexeme":"<","literal":null,"line":1,"column":10},` +
+		`{"type":"IDENTIFIER","lexeme":"a","literal":null,"line":1,"column":11},` +
+		`{"type":"SEMICOLON","lexeme":";","literal":null,"line":1,"column":12},` +
+		`{"type":"EOF","lexeme":"","literal":null,"line":1,"column":13}]`
+	assert.Equal(t, expected, string(out))
+}
@@ -0,0 +1,263 @@
+package lsp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/levpaul/glocks/internal/analysis"
+	"github.com/levpaul/glocks/internal/lexer"
+	"github.com/levpaul/glocks/internal/parser"
+	"github.com/levpaul/glocks/internal/resolver"
+	"go.uber.org/zap"
+)
+
+// declaration is a name declared in a document, such as a variable, parameter, function, class or method
+type declaration struct {
+	name   string
+	kind   string
+	detail string
+	pos    lexer.Position
+}
+
+// reference is a use of a name which the resolver bound to a declaration
+type reference struct {
+	name string
+	pos  lexer.Position
+	decl lexer.Position
+}
+
+// document is an open text document, along with everything the server knows about it from its last analysis
+type document struct {
+	uri          string
+	text         string
+	diagnostics  []Diagnostic
+	declarations []*declaration
+	references   []reference
+	symbols      []DocumentSymbol
+}
+
+// newDocument scans, parses, resolves and lints text, collecting diagnostics along with an index of declarations
+// and references. Analysis stops at the first stage which fails, so a document which doesn't parse only has
+// diagnostics.
+func newDocument(uri, text string) *document {
+	d := &document{uri: uri, text: text, diagnostics: []Diagnostic{}}
+	log := zap.NewNop().Sugar()
+
+	scanner := lexer.NewScanner(text, log)
+	tokens := scanner.ScanTokens()
+	for _, err := range scanner.Errors() {
+		d.addError(err)
+	}
+
+	stmts, err := parser.NewParser(log, tokens).Parse()
+	if err != nil {
+		d.addError(err)
+		return d
+	}
+
+	r := resolver.NewResolver()
+	if err = r.ResolveNodes(stmts); err != nil {
+		d.addError(err)
+	}
+	d.index(stmts, r)
+
+	if err == nil {
+		warnings, err := analysis.NewAnalyzer().Analyze(stmts)
+		if err == nil {
+			for _, w := range analysis.Suppress(text, warnings) {
+				d.diagnostics = append(d.diagnostics, Diagnostic{
+					Range:    toRange(w.Pos, d.wordLength(w.Pos)),
+					Severity: severityWarning,
+					Code:     w.Rule,
+					Source:   "glocks",
+					Message:  w.Message,
+				})
+			}
+		}
+	}
+	return d
+}
+
+// addError adds an error diagnostic, placed at the error's position if it has one
+func (d *document) addError(err error) {
+	var pos lexer.Position
+	var posErr *lexer.PositionError
+	if errors.As(err, &posErr) {
+		pos = posErr.Pos
+	}
+	d.diagnostics = append(d.diagnostics, Diagnostic{
+		Range:    toRange(pos, d.wordLength(pos)),
+		Severity: severityError,
+		Source:   "glocks",
+		Message:  err.Error(),
+	})
+}
+
+// wordLength returns the length of the identifier-like word at pos, or 1 if there is none so that
+// ranges are never empty
+func (d *document) wordLength(pos lexer.Position) int {
+	lines := strings.Split(d.text, "\n")
+	if pos.Line < 1 || pos.Line > len(lines) || pos.Column < 1 || pos.Column > len(lines[pos.Line-1]) {
+		return 1
+	}
+	line := lines[pos.Line-1][pos.Column-1:]
+	length := 0
+	for length < len(line) && lexer.IsIdentifierChar(rune(line[length])) {
+		length++
+	}
+	if length == 0 {
+		return 1
+	}
+	return length
+}
+
+// index walks the AST collecting declarations and document symbols, and every variable reference the
+// resolver was able to bind to a declaration
+func (d *document) index(stmts []parser.Node, r *resolver.Resolver) {
+	methodsOf := map[*parser.FunctionDeclaration]string{}
+	parser.InspectAll(stmts, func(node parser.Node) bool {
+		switch n := node.(type) {
+		case *parser.VarStmt:
+			d.declare(n.Name, "variable", "var "+n.Name, n.Position())
+		case *parser.ClassDeclaration:
+			kind := classKind(n)
+			detail := kind + " " + n.Name
+			if n.SuperClass != nil {
+				detail += " < " + n.SuperClass.TokenName
+			}
+			if len(n.Traits) > 0 {
+				names := make([]string, len(n.Traits))
+				for idx, t := range n.Traits {
+					names[idx] = t.TokenName
+				}
+				detail += " with " + strings.Join(names, ", ")
+			}
+			d.declare(n.Name, kind, detail, n.Position())
+			for _, m := range n.Methods {
+				if fd, ok := m.(*parser.FunctionDeclaration); ok {
+					methodsOf[fd] = n.Name
+				}
+			}
+		case *parser.FunctionDeclaration:
+			if class, isMethod := methodsOf[n]; isMethod && n.Static {
+				d.declare(n.Name, "method", fmt.Sprintf("class method %s.%s", class, signature(n)), n.Position())
+			} else if isMethod {
+				d.declare(n.Name, "method", fmt.Sprintf("method %s.%s", class, signature(n)), n.Position())
+			} else {
+				d.declare(n.Name, "function", "fun "+signature(n), n.Position())
+			}
+			for _, p := range n.Params {
+				d.declare(p.Lexeme, "parameter", fmt.Sprintf("parameter %s of %s", p.Lexeme, n.Name), p.Position())
+			}
+		case *parser.Variable:
+			d.reference(r, n, n.TokenName)
+		case *parser.Assignment:
+			d.reference(r, n, n.TokenName)
+		}
+		return true
+	})
+
+	for _, stmt := range stmts {
+		switch n := stmt.(type) {
+		case *parser.VarStmt:
+			d.symbols = append(d.symbols, d.symbol(n.Name, "var "+n.Name, symbolKindVariable, n.Position()))
+		case *parser.FunctionDeclaration:
+			d.symbols = append(d.symbols, d.symbol(n.Name, "fun "+signature(n), symbolKindFunction, n.Position()))
+		case *parser.ClassDeclaration:
+			class := d.symbol(n.Name, classKind(n)+" "+n.Name, symbolKindClass, n.Position())
+			for _, m := range n.Methods {
+				if fd, ok := m.(*parser.FunctionDeclaration); ok {
+					class.Children = append(class.Children, d.symbol(fd.Name, signature(fd), symbolKindMethod, fd.Position()))
+				}
+			}
+			d.symbols = append(d.symbols, class)
+		}
+	}
+}
+
+// classKind returns whether a class declaration declares a class or a trait
+func classKind(c *parser.ClassDeclaration) string {
+	if c.Trait {
+		return "trait"
+	}
+	return "class"
+}
+
+func (d *document) declare(name, kind, detail string, pos lexer.Position) {
+	d.declarations = append(d.declarations, &declaration{name: name, kind: kind, detail: detail, pos: pos})
+}
+
+func (d *document) reference(r *resolver.Resolver, node parser.Node, name string) {
+	if decl, found := r.Declaration(node); found {
+		d.references = append(d.references, reference{name: name, pos: node.Position(), decl: decl})
+	}
+}
+
+func (d *document) symbol(name, detail string, kind int, pos lexer.Position) DocumentSymbol {
+	return DocumentSymbol{
+		Name:           name,
+		Detail:         detail,
+		Kind:           kind,
+		Range:          toRange(pos, len(name)),
+		SelectionRange: toRange(pos, len(name)),
+	}
+}
+
+// signature returns a function's name and parameter list, e.g. `add(a, b)`, with parameters which have defaults
+// in brackets and a rest parameter prefixed with '...', as in `join(sep, [prefix], ...parts)`
+func signature(f *parser.FunctionDeclaration) string {
+	if f.Getter {
+		return f.Name
+	}
+	params := make([]string, len(f.Params))
+	for i, p := range f.Params {
+		params[i] = p.Lexeme
+		if f.Default(i) != nil {
+			params[i] = "[" + p.Lexeme + "]"
+		}
+	}
+	if f.Rest {
+		params[len(params)-1] = "..." + params[len(params)-1]
+	}
+	if f.Setter {
+		return fmt.Sprintf("set %s(%s)", f.Name, strings.Join(params, ", "))
+	}
+	return fmt.Sprintf("%s(%s)", f.Name, strings.Join(params, ", "))
+}
+
+// declarationAt returns the declaration of the name at an LSP position, whether the position is on the
+// declaration itself or on a reference to it
+func (d *document) declarationAt(pos Position) *declaration {
+	var declPos lexer.Position
+	found := false
+	for _, ref := range d.references {
+		if contains(ref.pos, len(ref.name), pos) {
+			declPos, found = ref.decl, true
+			break
+		}
+	}
+	for _, decl := range d.declarations {
+		if (found && decl.pos == declPos) || (!found && contains(decl.pos, len(decl.name), pos)) {
+			return decl
+		}
+	}
+	return nil
+}
+
+// referencesTo returns the locations of every reference to a declaration
+func (d *document) referencesTo(decl *declaration) []Location {
+	var locations []Location
+	for _, ref := range d.references {
+		if ref.decl == decl.pos {
+			locations = append(locations, Location{URI: d.uri, Range: toRange(ref.pos, len(ref.name))})
+		}
+	}
+	return locations
+}
+
+// contains reports whether an LSP position is within the name of the given length at a Lox position
+func contains(start lexer.Position, length int, pos Position) bool {
+	r := toRange(start, length)
+	return pos.Line == r.Start.Line && pos.Character >= r.Start.Character && pos.Character < r.End.Character
+}
@@ -0,0 +1,231 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Server is a Language Server Protocol server for Lox. It reads JSON-RPC messages from in and writes
+// responses and notifications to out, handling one message at a time. Documents are fully re-analyzed
+// on every change, which is quick enough for the size of typical Lox scripts.
+type Server struct {
+	in       *bufio.Reader
+	out      io.Writer
+	log      *zap.SugaredLogger
+	docs     map[string]*document
+	shutdown bool
+}
+
+// NewServer returns a Server which communicates over the given reader and writer, typically stdin and stdout
+func NewServer(in io.Reader, out io.Writer, log *zap.SugaredLogger) *Server {
+	return &Server{
+		in:   bufio.NewReader(in),
+		out:  out,
+		log:  log,
+		docs: map[string]*document{},
+	}
+}
+
+// Serve handles messages until the client sends an exit notification or closes the input. An error is
+// returned if the client exits without first requesting a shutdown.
+func (s *Server) Serve() error {
+	for {
+		msg, err := s.readMessage()
+		if err != nil {
+			if errors.Is(err, io.EOF) && s.shutdown {
+				return nil
+			}
+			return err
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit received before shutdown")
+			}
+			return nil
+		}
+
+		result, rpcErr := s.handle(msg)
+		if msg.ID == nil { // notifications never get a response
+			if rpcErr != nil {
+				s.log.With("error", rpcErr.Message).Debugf("Failed to handle notification '%s'", msg.Method)
+			}
+			continue
+		}
+		if err = s.writeMessage(&message{ID: msg.ID, Result: result, Error: rpcErr}); err != nil {
+			return err
+		}
+	}
+}
+
+// handle dispatches a message to its handler, returning the result for requests
+func (s *Server) handle(msg *message) (any, *responseError) {
+	switch msg.Method {
+	case "initialize":
+		res := initializeResult{}
+		res.Capabilities.TextDocumentSync = textDocumentSyncFull
+		res.Capabilities.DefinitionProvider = true
+		res.Capabilities.ReferencesProvider = true
+		res.Capabilities.HoverProvider = true
+		res.Capabilities.DocumentSymbolProvider = true
+		res.ServerInfo.Name = "glocks"
+		return res, nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return nil, s.update(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		// With full sync, the last change holds the entire document
+		return nil, s.update(params.TextDocument.URI, params.ContentChanges[len(params.ContentChanges)-1].Text)
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return nil, s.publishDiagnostics(params.TextDocument.URI, []Diagnostic{})
+
+	case "textDocument/definition":
+		doc, decl, rpcErr := s.declarationAt(msg.Params)
+		if rpcErr != nil || decl == nil {
+			return nil, rpcErr
+		}
+		return Location{URI: doc.uri, Range: toRange(decl.pos, len(decl.name))}, nil
+	case "textDocument/references":
+		var params referenceParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		doc, decl, rpcErr := s.declarationAt(msg.Params)
+		if rpcErr != nil || decl == nil {
+			return []Location{}, rpcErr
+		}
+		locations := []Location{}
+		if params.Context.IncludeDeclaration {
+			locations = append(locations, Location{URI: doc.uri, Range: toRange(decl.pos, len(decl.name))})
+		}
+		return append(locations, doc.referencesTo(decl)...), nil
+	case "textDocument/hover":
+		_, decl, rpcErr := s.declarationAt(msg.Params)
+		if rpcErr != nil || decl == nil {
+			return nil, rpcErr
+		}
+		return Hover{
+			Contents: markupContent{Kind: "markdown", Value: fmt.Sprintf("```lox\n%s\n```", decl.detail)},
+			Range:    toRange(decl.pos, len(decl.name)),
+		}, nil
+	case "textDocument/documentSymbol":
+		var params documentSymbolParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		doc, found := s.docs[params.TextDocument.URI]
+		if !found || doc.symbols == nil {
+			return []DocumentSymbol{}, nil
+		}
+		return doc.symbols, nil
+	}
+
+	return nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method '%s' is not supported", msg.Method)}
+}
+
+// update re-analyzes a document and publishes its diagnostics
+func (s *Server) update(uri, text string) *responseError {
+	doc := newDocument(uri, text)
+	s.docs[uri] = doc
+	return s.publishDiagnostics(uri, doc.diagnostics)
+}
+
+func (s *Server) publishDiagnostics(uri string, diagnostics []Diagnostic) *responseError {
+	params, err := json.Marshal(publishDiagnosticsParams{URI: uri, Diagnostics: diagnostics})
+	if err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	if err = s.writeMessage(&message{Method: "textDocument/publishDiagnostics", Params: params}); err != nil {
+		s.log.With("error", err).Error("Failed to publish diagnostics")
+	}
+	return nil
+}
+
+// declarationAt decodes text document position params, returning the document along with the declaration
+// of the name at that position, if there is one
+func (s *Server) declarationAt(raw json.RawMessage) (*document, *declaration, *responseError) {
+	var params textDocumentPositionParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, nil, invalidParams(err)
+	}
+	doc, found := s.docs[params.TextDocument.URI]
+	if !found {
+		return nil, nil, nil
+	}
+	return doc, doc.declarationAt(params.Position), nil
+}
+
+func invalidParams(err error) *responseError {
+	return &responseError{Code: codeInvalidParams, Message: err.Error()}
+}
+
+// readMessage reads a single message, which is a set of headers followed by a JSON body of Content-Length bytes
+func (s *Server) readMessage() (*message, error) {
+	headers, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(strings.TrimSpace(headers.Get("Content-Length")))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length header; err=%w", err)
+	}
+
+	body := make([]byte, length)
+	if _, err = io.ReadFull(s.in, body); err != nil {
+		return nil, err
+	}
+
+	msg := &message{}
+	if err = json.Unmarshal(body, msg); err != nil {
+		return nil, fmt.Errorf("invalid JSON-RPC message; err=%w", err)
+	}
+	return msg, nil
+}
+
+func (s *Server) writeMessage(msg *message) error {
+	msg.JSONRPC = "2.0"
+	if msg.ID != nil && msg.Result == nil && msg.Error == nil {
+		// Responses must have a result, even if it is null
+		return s.writeRaw(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":null}`, *msg.ID))
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return s.writeRaw(string(body))
+}
+
+func (s *Server) writeRaw(body string) error {
+	_, err := fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
@@ -0,0 +1,162 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const testURI = "file:///test.lox"
+
+const testProgram = `class Shape {
+  area() { return 0; }
+}
+fun double(n) {
+  var result = n * 2;
+  return result;
+}
+print double(2);
+print double(3) + undefinedVar;
+`
+
+// runSession sends each request in order to a new Server, followed by shutdown and exit, and returns every
+// message the server wrote
+func runSession(t *testing.T, requests ...string) []map[string]any {
+	var input strings.Builder
+	id := 1
+	for _, req := range append(requests, `{"method":"shutdown"}`, `{"method":"exit"}`) {
+		// Add an ID to requests, leaving notifications without one
+		if !strings.Contains(req, `"textDocument/did`) && !strings.Contains(req, `"exit"`) {
+			req = fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,%s`, id, req[1:])
+			id++
+		}
+		fmt.Fprintf(&input, "Content-Length: %d\r\n\r\n%s", len(req), req)
+	}
+
+	var output strings.Builder
+	require.NoError(t, NewServer(strings.NewReader(input.String()), &output, zap.S()).Serve())
+
+	var messages []map[string]any
+	reader := bufio.NewReader(strings.NewReader(output.String()))
+	for {
+		headers, err := textproto.NewReader(reader).ReadMIMEHeader()
+		if err == io.EOF {
+			return messages
+		}
+		require.NoError(t, err)
+		length, err := strconv.Atoi(headers.Get("Content-Length"))
+		require.NoError(t, err)
+		body := make([]byte, length)
+		_, err = io.ReadFull(reader, body)
+		require.NoError(t, err)
+
+		msg := map[string]any{}
+		require.NoError(t, json.Unmarshal(body, &msg))
+		messages = append(messages, msg)
+	}
+}
+
+func didOpen(text string) string {
+	textJSON, _ := json.Marshal(text)
+	return fmt.Sprintf(`{"method":"textDocument/didOpen","params":{"textDocument":{"uri":"%s","text":%s}}}`, testURI, textJSON)
+}
+
+func positionRequest(method string, line, character int) string {
+	return fmt.Sprintf(`{"method":"%s","params":{"textDocument":{"uri":"%s"},"position":{"line":%d,"character":%d},"context":{"includeDeclaration":true}}}`,
+		method, testURI, line, character)
+}
+
+func TestInitialize(t *testing.T) {
+	messages := runSession(t, `{"method":"initialize","params":{}}`)
+	require.Len(t, messages, 2)
+	capabilities := messages[0]["result"].(map[string]any)["capabilities"].(map[string]any)
+	assert.Equal(t, true, capabilities["definitionProvider"])
+	assert.Equal(t, true, capabilities["hoverProvider"])
+}
+
+func TestDiagnostics(t *testing.T) {
+	messages := runSession(t, didOpen("fun f() {\n  var unused = 1;\n}\nprint 1\n"))
+	require.Len(t, messages, 2)
+	assert.Equal(t, "textDocument/publishDiagnostics", messages[0]["method"])
+	diagnostics := messages[0]["params"].(map[string]any)["diagnostics"].([]any)
+	require.Len(t, diagnostics, 1)
+	assert.Contains(t, diagnostics[0].(map[string]any)["message"], "Expected ; after Statement")
+
+	messages = runSession(t, didOpen("fun f() {\n  var unused = 1;\n}\n"))
+	diagnostics = messages[0]["params"].(map[string]any)["diagnostics"].([]any)
+	require.Len(t, diagnostics, 1)
+	diagnostic := diagnostics[0].(map[string]any)
+	assert.Equal(t, "unused-variable", diagnostic["code"])
+	assert.Equal(t, map[string]any{
+		"start": map[string]any{"line": 1.0, "character": 6.0},
+		"end":   map[string]any{"line": 1.0, "character": 12.0},
+	}, diagnostic["range"])
+
+	// Ranges cover whole identifiers, including underscores and digits
+	messages = runSession(t, didOpen("fun f() {\n  var my_var2 = 1;\n}\n"))
+	diagnostics = messages[0]["params"].(map[string]any)["diagnostics"].([]any)
+	require.Len(t, diagnostics, 1)
+	assert.Equal(t, map[string]any{
+		"start": map[string]any{"line": 1.0, "character": 6.0},
+		"end":   map[string]any{"line": 1.0, "character": 13.0},
+	}, diagnostics[0].(map[string]any)["range"])
+
+	messages = runSession(t, didOpen("{ var a = a; }"))
+	diagnostics = messages[0]["params"].(map[string]any)["diagnostics"].([]any)
+	require.Len(t, diagnostics, 1)
+	assert.Contains(t, diagnostics[0].(map[string]any)["message"], "can't read local variable 'a' in its own initializer")
+}
+
+func TestDefinitionAndReferences(t *testing.T) {
+	messages := runSession(t,
+		didOpen(testProgram),
+		positionRequest("textDocument/definition", 5, 10), // `result` in the return statement
+		positionRequest("textDocument/references", 3, 5),  // `double` declaration
+		positionRequest("textDocument/definition", 8, 20), // `undefinedVar`, which has no declaration
+		positionRequest("textDocument/hover", 7, 7),       // `double` call
+		`{"method":"textDocument/documentSymbol","params":{"textDocument":{"uri":"`+testURI+`"}}}`,
+	)
+	require.Len(t, messages, 7)
+
+	definition := messages[1]["result"].(map[string]any)
+	assert.Equal(t, map[string]any{"line": 4.0, "character": 6.0}, definition["range"].(map[string]any)["start"])
+
+	references := messages[2]["result"].([]any)
+	var lines []float64
+	for _, ref := range references {
+		lines = append(lines, ref.(map[string]any)["range"].(map[string]any)["start"].(map[string]any)["line"].(float64))
+	}
+	assert.Equal(t, []float64{3, 7, 8}, lines)
+
+	assert.Nil(t, messages[3]["result"])
+
+	hover := messages[4]["result"].(map[string]any)["contents"].(map[string]any)
+	assert.Equal(t, "```lox\nfun double(n)\n```", hover["value"])
+
+	symbols := messages[5]["result"].([]any)
+	require.Len(t, symbols, 2)
+	class := symbols[0].(map[string]any)
+	assert.Equal(t, "Shape", class["name"])
+	assert.Equal(t, "area", class["children"].([]any)[0].(map[string]any)["name"])
+	assert.Equal(t, "double", symbols[1].(map[string]any)["name"])
+}
+
+func TestHoverSignatureWithDefaultAndRestParameters(t *testing.T) {
+	messages := runSession(t,
+		didOpen("fun join(sep, prefix = \"\", ...parts) {}\njoin(\",\");\n"),
+		positionRequest("textDocument/hover", 1, 1),
+	)
+	require.Len(t, messages, 3)
+
+	hover := messages[1]["result"].(map[string]any)["contents"].(map[string]any)
+	assert.Equal(t, "```lox\nfun join(sep, [prefix], ...parts)\n```", hover["value"])
+}
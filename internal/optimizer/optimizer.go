@@ -0,0 +1,214 @@
+// Package optimizer simplifies a resolved AST before it is run, folding constant expressions and removing code
+// which can never run. It never changes the behaviour of a program, including which runtime errors it fails with:
+// expressions which would fail, such as "a" + 1, are left to fail when they are evaluated.
+package optimizer
+
+import (
+	"github.com/levpaul/glocks/internal/lexer"
+	"github.com/levpaul/glocks/internal/parser"
+)
+
+// Optimize returns the optimized statements of a program. Nodes are changed in place rather than copied, and only
+// expressions are ever replaced by new nodes, so the bindings the resolver recorded for variables, blocks and
+// functions still apply.
+func Optimize(stmts []parser.Node) []parser.Node {
+	return statements(stmts)
+}
+
+// statements optimizes a list of statements, dropping those which are removed and any after a return
+func statements(stmts []parser.Node) []parser.Node {
+	optimized := make([]parser.Node, 0, len(stmts))
+	for _, s := range stmts {
+		s = statement(s)
+		if s == nil {
+			continue
+		}
+		optimized = append(optimized, s)
+		if _, isReturn := s.(*parser.ReturnStmt); isReturn {
+			break
+		}
+	}
+	return optimized
+}
+
+// statement optimizes a single statement, returning nil when it can be removed entirely
+func statement(stmt parser.Node) parser.Node {
+	switch s := stmt.(type) {
+	case nil:
+		return nil
+	case *parser.PrintStmt:
+		s.Arg = expression(s.Arg)
+	case *parser.VarStmt:
+		if s.Initializer != nil {
+			s.Initializer = expression(s.Initializer)
+		}
+	case *parser.ReturnStmt:
+		if s.Expression != nil {
+			s.Expression = expression(s.Expression)
+		}
+	case *parser.YieldStmt:
+		if s.Expression != nil {
+			s.Expression = expression(s.Expression)
+		}
+	case *parser.Block:
+		s.Statements = statements(s.Statements)
+	case *parser.IfStmt:
+		s.Expression = expression(s.Expression)
+		if cond, isConst := s.Expression.(*parser.Literal); isConst {
+			if isTruthy(cond.Value) {
+				return statement(s.Statement)
+			}
+			return statement(s.ElseStatement)
+		}
+		s.Statement = orEmpty(statement(s.Statement), s)
+		s.ElseStatement = statement(s.ElseStatement)
+	case *parser.WhileStmt:
+		s.Expression = expression(s.Expression)
+		if cond, isConst := s.Expression.(*parser.Literal); isConst && !isTruthy(cond.Value) {
+			return nil
+		}
+		s.Body = orEmpty(statement(s.Body), s)
+	case *parser.ForInStmt:
+		s.Iterable = expression(s.Iterable)
+		s.Body = orEmpty(statement(s.Body), s)
+	case *parser.FunctionDeclaration:
+		for idx, def := range s.Defaults {
+			if def != nil {
+				s.Defaults[idx] = expression(def)
+			}
+		}
+		s.Body = statements(s.Body)
+	case *parser.ClassDeclaration:
+		for _, m := range s.Methods {
+			statement(m)
+		}
+	default:
+		// Any other node is an expression statement. It is kept even when it's constant, as the REPL prints the
+		// value of expression statements.
+		return expression(stmt)
+	}
+	return stmt
+}
+
+// orEmpty returns stmt, or an empty block positioned at parent when stmt was removed, for statements which
+// must have a body
+func orEmpty(stmt, parent parser.Node) parser.Node {
+	if stmt == nil {
+		return &parser.Block{Pos: parser.Pos(parent.Position())}
+	}
+	return stmt
+}
+
+// expression folds an expression, returning a literal in its place when its value is known without running it
+func expression(expr parser.Node) parser.Node {
+	switch e := expr.(type) {
+	case *parser.Grouping:
+		e.Expression = expression(e.Expression)
+		if lit, isConst := e.Expression.(*parser.Literal); isConst {
+			return lit
+		}
+	case *parser.Unary:
+		e.Right = expression(e.Right)
+		if right, isConst := e.Right.(*parser.Literal); isConst {
+			if value, folded := foldUnary(e.Operator.Type, right.Value); folded {
+				return &parser.Literal{Pos: e.Pos, Value: value}
+			}
+		}
+	case *parser.Binary:
+		e.Left = expression(e.Left)
+		e.Right = expression(e.Right)
+		left, leftConst := e.Left.(*parser.Literal)
+		right, rightConst := e.Right.(*parser.Literal)
+		if leftConst && rightConst {
+			if value, folded := foldBinary(e.Operator.Type, left.Value, right.Value); folded {
+				return &parser.Literal{Pos: e.Pos, Value: value}
+			}
+		}
+	case *parser.LogicalConjuction:
+		e.Left = expression(e.Left)
+		e.Right = expression(e.Right)
+		// A constant left operand decides whether the right is evaluated, and which operand is the result
+		if left, isConst := e.Left.(*parser.Literal); isConst {
+			if isTruthy(left.Value) == e.And {
+				return e.Right
+			}
+			return left
+		}
+	case *parser.Assignment:
+		e.Value = expression(e.Value)
+	case *parser.CallExpr:
+		e.Callee = expression(e.Callee)
+		for idx, arg := range e.Args {
+			e.Args[idx] = expression(arg)
+		}
+	case *parser.GetExpr:
+		e.Instance = expression(e.Instance)
+	case *parser.SetExpr:
+		e.Instance = expression(e.Instance)
+		e.Value = expression(e.Value)
+	}
+	return expr
+}
+
+// foldUnary evaluates a unary operator on a constant, reporting false when the operation would fail at runtime
+func foldUnary(op lexer.TokenType, right any) (any, bool) {
+	switch op {
+	case lexer.BANG:
+		return !isTruthy(right), true
+	case lexer.MINUS:
+		if n, isNum := right.(float64); isNum {
+			return -n, true
+		}
+	}
+	return nil, false
+}
+
+// foldBinary evaluates a binary operator on constants, reporting false when the operation would fail at runtime
+func foldBinary(op lexer.TokenType, left, right any) (any, bool) {
+	switch op {
+	case lexer.EQUAL_EQUAL:
+		return left == right, true
+	case lexer.BANG_EQUAL:
+		return left != right, true
+	}
+
+	if l, isStr := left.(string); isStr {
+		if r, isStr := right.(string); isStr && op == lexer.PLUS {
+			return l + r, true
+		}
+		return nil, false
+	}
+
+	l, lNum := left.(float64)
+	r, rNum := right.(float64)
+	if !lNum || !rNum {
+		return nil, false
+	}
+	switch op {
+	case lexer.PLUS:
+		return l + r, true
+	case lexer.MINUS:
+		return l - r, true
+	case lexer.STAR:
+		return l * r, true
+	case lexer.SLASH:
+		return l / r, true
+	case lexer.LESS:
+		return l < r, true
+	case lexer.LESS_EQUAL:
+		return l <= r, true
+	case lexer.GREATER:
+		return l > r, true
+	case lexer.GREATER_EQUAL:
+		return l >= r, true
+	}
+	return nil, false
+}
+
+// isTruthy matches the interpreter's truthiness, where only nil and false are falsy
+func isTruthy(v any) bool {
+	if b, isBool := v.(bool); isBool {
+		return b
+	}
+	return v != nil
+}
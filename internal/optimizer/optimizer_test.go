@@ -0,0 +1,60 @@
+package optimizer
+
+import (
+	"testing"
+
+	"github.com/levpaul/glocks/internal/lexer"
+	"github.com/levpaul/glocks/internal/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func optimize(t *testing.T, source string) string {
+	log := zap.NewNop().Sugar()
+	stmts, err := parser.NewParser(log, lexer.NewScanner(source, log).ScanTokens()).Parse()
+	require.NoError(t, err)
+	printer := parser.ExprPrinter{}
+	return printer.PrintProgram(Optimize(stmts))
+}
+
+func TestFoldExpressions(t *testing.T) {
+	cases := map[string]string{
+		`print 1 + 2 * 3;`:         "(print 7)\n",
+		`print (1 + 2) * -3;`:      "(print -9)\n",
+		`print "a" + "b" + "c";`:   "(print \"abc\")\n",
+		`print !true;`:             "(print false)\n",
+		`print !(1 < 2) == false;`: "(print true)\n",
+		`print 1 >= 2 or x;`:       "(print x)\n",
+		`print nil and x;`:         "(print nil)\n",
+		`print x + (2 * 3);`:       "(print (+ x 6))\n",
+		`f(1 + 1, a.b = 2 - 1);`:   "(call f 2 (set a b 1))\n",
+		`var x = "n" + 1;`:         "(var x (+ \"n\" 1))\n",
+		`print -"a";`:              "(print (- \"a\"))\n",
+		`print 1 < "a";`:           "(print (< 1 \"a\"))\n",
+		`print "a" == "a";`:        "(print true)\n",
+		`print 1 == "1";`:          "(print false)\n",
+		`x = 2 > 1;`:               "(= x true)\n",
+	}
+	for source, expected := range cases {
+		assert.Equal(t, expected, optimize(t, source), "optimizing: %s", source)
+	}
+}
+
+func TestRemoveDeadCode(t *testing.T) {
+	cases := map[string]string{
+		`if (false) print 1;`:                      "",
+		`if (1 > 2) print 1; else print 2;`:        "(print 2)\n",
+		`if (true) { print 1; } else print 2;`:     "(block\n  (print 1))\n",
+		`if (x) print 1; else if (false) print 2;`: "(if x (print 1))\n",
+		`if (x) if (false) print 1;`:               "(if x (block))\n",
+		`while (false) print 1; print 2;`:          "(print 2)\n",
+		`while (x) if (false) print 1;`:            "(while x (block))\n",
+		`fun f() { return 1; print 2; }`:           "(fun f ()\n  (return 1))\n",
+		`{ print 1; return; print 2; } print 3;`:   "(block\n  (print 1)\n  (return))\n(print 3)\n",
+		`class A { m() { return; print 1; } }`:     "(class A\n  (fun m ()\n    (return)))\n",
+	}
+	for source, expected := range cases {
+		assert.Equal(t, expected, optimize(t, source), "optimizing: %s", source)
+	}
+}
@@ -0,0 +1,179 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// MarshalJSON serialises the statements of a program as a JSON array of nodes, for tools which consume parse
+// trees. Every node is an object starting with its "kind", which is the name of its Go type such as "Binary",
+// followed by the "line" and "column" it was parsed from, and then its fields in a fixed order. Child nodes are
+// nested objects, and missing children, such as an if statement without an else, are null.
+func MarshalJSON(stmts []Node) ([]byte, error) {
+	return marshalNoEscape(nodesJSON(stmts))
+}
+
+// marshalNoEscape encodes v as JSON without escaping characters like '<' for embedding in HTML, as operators
+// would otherwise be unreadable
+func marshalNoEscape(v any) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// jsonField is a single field of a jsonObject
+type jsonField struct {
+	key   string
+	value any
+}
+
+// jsonObject is a JSON object which keeps its fields in the order they were added, so the output is stable and
+// the kind of each node comes first. A nil jsonObject is encoded as null.
+type jsonObject []jsonField
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	if o == nil {
+		return []byte("null"), nil
+	}
+	buf := &bytes.Buffer{}
+	buf.WriteByte('{')
+	for idx, f := range o {
+		if idx > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := marshalNoEscape(f.key)
+		buf.Write(key)
+		buf.WriteByte(':')
+		value, err := marshalNoEscape(f.value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode field '%s': %w", f.key, err)
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func nodesJSON(nodes []Node) []jsonObject {
+	objects := make([]jsonObject, len(nodes))
+	for idx, n := range nodes {
+		objects[idx] = nodeJSON(n)
+	}
+	return objects
+}
+
+// nodeJSON returns the JSON object of a node and its children, or nil if there is no node. As every node is a
+// pointer, a nil pointer such as a class without a superclass is also no node.
+func nodeJSON(node Node) jsonObject {
+	if node == nil || reflect.ValueOf(node).IsNil() {
+		return nil
+	}
+
+	pos := node.Position()
+	o := jsonObject{
+		{"kind", reflect.TypeOf(node).Elem().Name()},
+		{"line", pos.Line},
+		{"column", pos.Column},
+	}
+	add := func(key string, value any) {
+		o = append(o, jsonField{key, value})
+	}
+
+	switch n := node.(type) {
+	case *SetExpr:
+		add("object", nodeJSON(n.Instance))
+		add("name", n.Name.Lexeme)
+		add("value", nodeJSON(n.Value))
+	case *GetExpr:
+		add("object", nodeJSON(n.Instance))
+		add("name", n.Name.Lexeme)
+	case *SuperExpr:
+		add("method", n.Method.Lexeme)
+	case *ThisExpr:
+		// this has nothing but its kind and position
+	case *ClassDeclaration:
+		add("name", n.Name)
+		add("trait", n.Trait)
+		add("superclass", nodeJSON(n.SuperClass))
+		add("traits", nodesJSON(variableNodes(n.Traits)))
+		add("methods", nodesJSON(n.Methods))
+	case *ReturnStmt:
+		add("value", nodeJSON(n.Expression))
+	case *YieldStmt:
+		add("value", nodeJSON(n.Expression))
+	case *FunctionDeclaration:
+		params := make([]string, len(n.Params))
+		defaults := make([]jsonObject, len(n.Params))
+		for idx, p := range n.Params {
+			params[idx] = p.Lexeme
+			defaults[idx] = nodeJSON(n.Default(idx))
+		}
+		add("name", n.Name)
+		add("params", params)
+		add("defaults", defaults)
+		add("rest", n.Rest)
+		add("body", nodesJSON(n.Body))
+		add("static", n.Static)
+		add("getter", n.Getter)
+		add("setter", n.Setter)
+	case *CallExpr:
+		names := make([]*string, len(n.Args))
+		for idx, name := range n.ArgNames {
+			if name != nil {
+				names[idx] = &name.Lexeme
+			}
+		}
+		add("callee", nodeJSON(n.Callee))
+		add("args", nodesJSON(n.Args))
+		add("argNames", names)
+	case *WhileStmt:
+		add("condition", nodeJSON(n.Expression))
+		add("body", nodeJSON(n.Body))
+	case *ForInStmt:
+		add("variable", nodeJSON(n.Variable))
+		add("iterable", nodeJSON(n.Iterable))
+		add("body", nodeJSON(n.Body))
+	case *LogicalConjuction:
+		op := "or"
+		if n.And {
+			op = "and"
+		}
+		add("operator", op)
+		add("left", nodeJSON(n.Left))
+		add("right", nodeJSON(n.Right))
+	case *IfStmt:
+		add("condition", nodeJSON(n.Expression))
+		add("then", nodeJSON(n.Statement))
+		add("else", nodeJSON(n.ElseStatement))
+	case *Block:
+		add("statements", nodesJSON(n.Statements))
+	case *Binary:
+		add("operator", n.Operator.Lexeme)
+		add("left", nodeJSON(n.Left))
+		add("right", nodeJSON(n.Right))
+	case *Grouping:
+		add("expression", nodeJSON(n.Expression))
+	case *Literal:
+		add("value", n.Value)
+	case *Unary:
+		add("operator", n.Operator.Lexeme)
+		add("right", nodeJSON(n.Right))
+	case *Variable:
+		add("name", n.TokenName)
+	case *Assignment:
+		add("name", n.TokenName)
+		add("value", nodeJSON(n.Value))
+	case *PrintStmt:
+		add("expression", nodeJSON(n.Arg))
+	case *VarStmt:
+		add("name", n.Name)
+		add("initializer", nodeJSON(n.Initializer))
+	}
+	return o
+}
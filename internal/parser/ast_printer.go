@@ -2,78 +2,162 @@ package parser
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
+// ExprPrinter prints nodes in a Lisp like syntax, with each node as a parenthesised list of its name followed by
+// its children. Expressions are printed on a single line, while the statements of blocks, function bodies and
+// classes are each printed on their own line, indented by how deeply they are nested.
 type ExprPrinter struct {
 	res string
+	// depth is how many statement lists the node being printed is nested within
+	depth int
 }
 
 func (e *ExprPrinter) VisitSuperExpr(s *SuperExpr) error {
-	return reflectPrint(s)
+	e.res = e.parenthesizeParts("super", s.Method.Lexeme)
+	return nil
 }
 
 func (e *ExprPrinter) VisitThisExpr(t *ThisExpr) error {
-	return reflectPrint(t)
+	e.res = "this"
+	return nil
 }
 
 // VisitSetExpr implements Visitor.
 func (e *ExprPrinter) VisitSetExpr(s *SetExpr) error {
-	return reflectPrint(s)
-}
-
-func reflectPrint(i interface{}) error {
-	fmt.Printf("%+v\n", i)
+	e.res = e.parenthesizeParts("set", e.Print(s.Instance), s.Name.Lexeme, e.Print(s.Value))
 	return nil
 }
 
 // VisitGetExpr implements Visitor.
 func (e *ExprPrinter) VisitGetExpr(g *GetExpr) error {
-	return reflectPrint(g)
+	e.res = e.parenthesizeParts("get", e.Print(g.Instance), g.Name.Lexeme)
+	return nil
 }
 
 func (e *ExprPrinter) VisitClassDeclaration(c *ClassDeclaration) error {
-	return reflectPrint(c)
+	head := "class " + c.Name
+	if c.Trait {
+		head = "trait " + c.Name
+	}
+	if c.SuperClass != nil {
+		head += " (< " + c.SuperClass.TokenName + ")"
+	}
+	if len(c.Traits) > 0 {
+		head += " " + e.parenthesize("with", variableNodes(c.Traits)...)
+	}
+	e.res = e.parenthesizeBody(head, c.Methods)
+	return nil
 }
 
 func (e *ExprPrinter) VisitReturnStmt(r *ReturnStmt) error {
-	return reflectPrint(r)
+	if r.Expression == nil {
+		e.res = "(return)"
+		return nil
+	}
+	e.res = e.parenthesize("return", r.Expression)
+	return nil
+}
+
+func (e *ExprPrinter) VisitYieldStmt(y *YieldStmt) error {
+	if y.Expression == nil {
+		e.res = "(yield)"
+		return nil
+	}
+	e.res = e.parenthesize("yield", y.Expression)
+	return nil
 }
 
 func (e *ExprPrinter) VisitFunctionDeclaration(f *FunctionDeclaration) error {
-	return reflectPrint(f)
+	params := make([]string, len(f.Params))
+	for idx, p := range f.Params {
+		params[idx] = p.Lexeme
+		if def := f.Default(idx); def != nil {
+			params[idx] = e.parenthesizeParts("=", p.Lexeme, e.Print(def))
+		}
+	}
+	if f.Rest {
+		params[len(params)-1] = "..." + params[len(params)-1]
+	}
+	head := fmt.Sprintf("fun %s (%s)", f.Name, strings.Join(params, " "))
+	switch {
+	case f.Getter:
+		head = "getter fun " + f.Name
+	case f.Setter:
+		head = "setter " + head
+	}
+	if f.Static {
+		head = "class " + head
+	}
+	e.res = e.parenthesizeBody(head, f.Body)
+	return nil
 }
 
 func (e *ExprPrinter) VisitCallExpr(f *CallExpr) error {
-	return reflectPrint(f)
+	parts := []string{e.Print(f.Callee)}
+	for idx, arg := range f.Args {
+		if idx < len(f.ArgNames) && f.ArgNames[idx] != nil {
+			parts = append(parts, e.parenthesizeParts(":", f.ArgNames[idx].Lexeme, e.Print(arg)))
+			continue
+		}
+		parts = append(parts, e.Print(arg))
+	}
+	e.res = e.parenthesizeParts("call", parts...)
+	return nil
 }
 
 func (e *ExprPrinter) VisitWhileStmt(w *WhileStmt) error {
-	return reflectPrint(w)
+	e.res = e.parenthesize("while", w.Expression, w.Body)
+	return nil
+}
+
+func (e *ExprPrinter) VisitForInStmt(f *ForInStmt) error {
+	e.res = e.parenthesize("for-in", f.Variable, f.Iterable, f.Body)
+	return nil
 }
 
 func (e *ExprPrinter) VisitLogicalConjunction(v *LogicalConjuction) error {
-	return reflectPrint(v)
+	op := "or"
+	if v.And {
+		op = "and"
+	}
+	e.res = e.parenthesize(op, v.Left, v.Right)
+	return nil
 }
 
 func (e *ExprPrinter) VisitIfStmt(i *IfStmt) error {
-	return reflectPrint(i)
+	if i.ElseStatement == nil {
+		e.res = e.parenthesize("if", i.Expression, i.Statement)
+		return nil
+	}
+	e.res = e.parenthesize("if", i.Expression, i.Statement, i.ElseStatement)
+	return nil
 }
 
 func (e *ExprPrinter) VisitBlock(b *Block) error {
-	return reflectPrint(b)
+	e.res = e.parenthesizeBody("block", b.Statements)
+	return nil
 }
 
 func (e *ExprPrinter) VisitAssignment(v *Assignment) error {
-	return reflectPrint(v)
+	e.res = e.parenthesizeParts("=", v.TokenName, e.Print(v.Value))
+	return nil
 }
 
 func (e *ExprPrinter) VisitVariable(v *Variable) error {
-	return reflectPrint(v)
+	e.res = v.TokenName
+	return nil
 }
 
 func (e *ExprPrinter) VisitVarStmt(v *VarStmt) error {
-	return reflectPrint(v)
+	if v.Initializer == nil {
+		e.res = e.parenthesizeParts("var", v.Name)
+		return nil
+	}
+	e.res = e.parenthesizeParts("var", v.Name, e.Print(v.Initializer))
+	return nil
 }
 
 func (e *ExprPrinter) VisitBinary(b *Binary) error {
@@ -89,6 +173,12 @@ func (e *ExprPrinter) VisitGrouping(g *Grouping) error {
 func (e *ExprPrinter) VisitLiteral(l *Literal) error {
 	if l.Value == nil {
 		e.res = "nil"
+		return nil
+	}
+	// Strings are quoted to tell them apart from other literals and identifiers, such as "nil" from nil
+	if str, isString := l.Value.(string); isString {
+		e.res = strconv.Quote(str)
+		return nil
 	}
 	e.res = fmt.Sprintf("%+v", l.Value)
 	return nil
@@ -114,15 +204,61 @@ func (e *ExprPrinter) Print(stmt Node) string {
 	return e.res
 }
 
+// PrintProgram prints each of the statements of a program on its own line
+func (e *ExprPrinter) PrintProgram(stmts []Node) string {
+	builder := strings.Builder{}
+	for _, stmt := range stmts {
+		builder.WriteString(e.Print(stmt))
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
 func (e *ExprPrinter) parenthesize(name string, exprs ...Node) string {
+	parts := make([]string, len(exprs))
+	for idx, expr := range exprs {
+		parts[idx] = e.Print(expr)
+	}
+	return e.parenthesizeParts(name, parts...)
+}
+
+// parenthesizeParts prints a list of a name followed by already printed parts
+func (e *ExprPrinter) parenthesizeParts(name string, parts ...string) string {
 	builder := strings.Builder{}
 	builder.WriteString("(")
 	builder.WriteString(name)
 
-	for _, expr := range exprs {
+	for _, part := range parts {
 		builder.WriteString(" ")
-		builder.WriteString(e.Print(expr))
+		builder.WriteString(part)
+	}
+
+	builder.WriteString(")")
+	return builder.String()
+}
+
+// variableNodes returns variables as a list of nodes
+func variableNodes(vars []*Variable) []Node {
+	nodes := make([]Node, len(vars))
+	for idx, v := range vars {
+		nodes[idx] = v
+	}
+	return nodes
+}
+
+// parenthesizeBody prints a list of a head followed by a list of statements, each on its own indented line
+func (e *ExprPrinter) parenthesizeBody(head string, stmts []Node) string {
+	builder := strings.Builder{}
+	builder.WriteString("(")
+	builder.WriteString(head)
+
+	e.depth++
+	for _, stmt := range stmts {
+		builder.WriteString("\n")
+		builder.WriteString(strings.Repeat("  ", e.depth))
+		builder.WriteString(e.Print(stmt))
 	}
+	e.depth--
 
 	builder.WriteString(")")
 	return builder.String()
@@ -5,6 +5,8 @@ import (
 
 	"github.com/levpaul/glocks/internal/lexer"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 )
 
 func TestPrintExpression(t *testing.T) {
@@ -31,3 +33,76 @@ func TestPrintExpression(t *testing.T) {
 	walker := ExprPrinter{}
 	assert.Equal(t, "(* (- 123) (group 45.67))", walker.Print(&expr))
 }
+
+func TestPrintStringLiterals(t *testing.T) {
+	source := "print \"a b\"; print \"nil\"; print nil; print \"true\" == true; print \"two\nlines\" + x;"
+	expected := `(print "a b")
+(print "nil")
+(print nil)
+(print (== "true" true))
+(print (+ "two\nlines" x))
+`
+	printer := ExprPrinter{}
+	assert.Equal(t, expected, printer.PrintProgram(parseProgram(t, source)))
+}
+
+func parseProgram(t *testing.T, source string) []Node {
+	stmts, err := NewParser(zap.NewNop().Sugar(), lexer.NewScanner(source, zap.NewNop().Sugar()).ScanTokens()).Parse()
+	require.NoError(t, err)
+	return stmts
+}
+
+func TestPrintProgram(t *testing.T) {
+	source := `class A < B {
+  init(v) { this.v = v; super.init(); }
+  class make() { return this(1); }
+}
+trait T with U, V { area { return 1; } }
+fun f() { return; }
+fun g() { yield; yield 1; }
+fun h(a, b = 2, ...rest) { h(a, b: 3); }
+var x;
+var y = nil;
+if (x or y) print a.b; else { x = f(1, 2); }
+while (false) {}
+for (var c in "ab") print c;
+`
+	expected := `(class A (< B)
+  (fun init (v)
+    (set this v v)
+    (call (super init)))
+  (class fun make ()
+    (return (call this 1))))
+(trait T (with U V)
+  (getter fun area
+    (return 1)))
+(fun f ()
+  (return))
+(fun g ()
+  (yield)
+  (yield 1))
+(fun h (a (= b 2) ...rest)
+  (call h a (: b 3)))
+(var x)
+(var y nil)
+(if (or x y) (print (get a b)) (block
+  (= x (call f 1 2))))
+(while false (block))
+(for-in (var c) "ab" (print c))
+`
+	printer := ExprPrinter{}
+	assert.Equal(t, expected, printer.PrintProgram(parseProgram(t, source)))
+}
+
+func TestMarshalJSON(t *testing.T) {
+	out, err := MarshalJSON(parseProgram(t, "var x = -1;\nif (x) print x < 2;"))
+	require.NoError(t, err)
+
+	expected := `[{"kind":"VarStmt","line":1,"column":5,"name":"x","initializer":` +
+		`{"kind":"Unary","line":1,"column":9,"operator":"-","right":{"kind":"Literal","line":1,"column":10,"value":1}}},` +
+		`{"kind":"IfStmt","line":2,"column":1,"condition":{"kind":"Variable","line":2,"column":5,"name":"x"},` +
+		`"then":{"kind":"PrintStmt","line":2,"column":8,"expression":{"kind":"Binary","line":2,"column":16,"operator":"<",` +
+		`"left":{"kind":"Variable","line":2,"column":14,"name":"x"},"right":{"kind":"Literal","line":2,"column":18,"value":2}}},` +
+		`"else":null}]`
+	assert.Equal(t, expected, string(out))
+}
@@ -0,0 +1,80 @@
+package parser
+
+// Inspect traverses an AST in depth-first order, in the same way as go/ast.Inspect. It calls f(node) for each
+// node, and if f returns true, Inspect then visits each of the node's children. Nil children are skipped.
+func Inspect(node Node, f func(Node) bool) {
+	if node == nil || !f(node) {
+		return
+	}
+
+	for _, child := range Children(node) {
+		Inspect(child, f)
+	}
+}
+
+// InspectAll calls Inspect on each of the given nodes in turn
+func InspectAll(nodes []Node, f func(Node) bool) {
+	for _, node := range nodes {
+		Inspect(node, f)
+	}
+}
+
+// Children returns the direct child nodes of a node, in source order
+func Children(node Node) []Node {
+	var children []Node
+	add := func(nodes ...Node) {
+		for _, n := range nodes {
+			if n != nil {
+				children = append(children, n)
+			}
+		}
+	}
+
+	switch n := node.(type) {
+	case *SetExpr:
+		add(n.Instance, n.Value)
+	case *GetExpr:
+		add(n.Instance)
+	case *ClassDeclaration:
+		if n.SuperClass != nil {
+			add(n.SuperClass)
+		}
+		for _, t := range n.Traits {
+			add(t)
+		}
+		add(n.Methods...)
+	case *ReturnStmt:
+		add(n.Expression)
+	case *YieldStmt:
+		add(n.Expression)
+	case *FunctionDeclaration:
+		add(n.Defaults...)
+		add(n.Body...)
+	case *CallExpr:
+		add(n.Callee)
+		add(n.Args...)
+	case *WhileStmt:
+		add(n.Expression, n.Body)
+	case *ForInStmt:
+		add(n.Variable, n.Iterable, n.Body)
+	case *LogicalConjuction:
+		add(n.Left, n.Right)
+	case *IfStmt:
+		add(n.Expression, n.Statement, n.ElseStatement)
+	case *Block:
+		add(n.Statements...)
+	case *Binary:
+		add(n.Left, n.Right)
+	case *Grouping:
+		add(n.Expression)
+	case *Unary:
+		add(n.Right)
+	case *Assignment:
+		add(n.Value)
+	case *PrintStmt:
+		add(n.Arg)
+	case *VarStmt:
+		add(n.Initializer)
+	}
+	return children
+}
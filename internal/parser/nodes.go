@@ -1,12 +1,16 @@
 package parser
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/levpaul/glocks/internal/domain"
 	"github.com/levpaul/glocks/internal/environment"
 	"github.com/levpaul/glocks/internal/lexer"
 )
 
 type ThisExpr struct {
+	Pos
 	Keyword *lexer.Token
 }
 
@@ -15,6 +19,7 @@ func (t *ThisExpr) Accept(v Visitor) error {
 }
 
 type SetExpr struct {
+	Pos
 	Instance Node
 	Name     *lexer.Token
 	Value    Node
@@ -27,6 +32,7 @@ func (s *SetExpr) Accept(v Visitor) error {
 // GetExpr is a node that represents a get expression - that is a dot expression
 // that gets a property from an instance of a class.
 type GetExpr struct {
+	Pos
 	Instance Node
 	Name     *lexer.Token
 }
@@ -36,6 +42,7 @@ func (g *GetExpr) Accept(v Visitor) error {
 }
 
 type SuperExpr struct {
+	Pos
 	Keyword *lexer.Token
 	Method  *lexer.Token
 }
@@ -44,11 +51,16 @@ func (s *SuperExpr) Accept(v Visitor) error {
 	return v.VisitSuperExpr(s)
 }
 
-// ClassDeclaration is a node that represents a class declaration.
+// ClassDeclaration is a node that represents a class declaration, or a trait declaration when Trait is set.
 type ClassDeclaration struct {
+	Pos
 	Name       string
 	Methods    []Node
 	SuperClass *Variable
+	// Traits are the traits whose methods are included in the class, or in the trait
+	Traits []*Variable
+	// Trait is set for a trait, which holds methods to be included in classes and can't be called
+	Trait bool
 }
 
 func (c *ClassDeclaration) Accept(v Visitor) error {
@@ -56,6 +68,7 @@ func (c *ClassDeclaration) Accept(v Visitor) error {
 }
 
 type ReturnStmt struct {
+	Pos
 	Expression Node
 }
 
@@ -63,20 +76,56 @@ func (r *ReturnStmt) Accept(v Visitor) error {
 	return v.VisitReturnStmt(r)
 }
 
+// YieldStmt suspends the generator function it is within, producing the value of Expression, or nil when there
+// is none
+type YieldStmt struct {
+	Pos
+	Expression Node
+}
+
+func (y *YieldStmt) Accept(v Visitor) error {
+	return v.VisitYieldStmt(y)
+}
+
 type FunctionDeclaration struct {
+	Pos
 	Name   string
-	Params []string
-	Body   []Node
+	Params []*lexer.Token
+	// Defaults are the default values of Params, evaluated in the function's environment when a call leaves the
+	// parameter out. They are nil for parameters without one, and Defaults itself is nil when no parameter has one.
+	Defaults []Node
+	// Rest is true when the last parameter collects any further arguments into a list
+	Rest bool
+	Body []Node
+	// Static is true for class methods, declared with a leading 'class' keyword, which are called on the class
+	// itself rather than its instances
+	Static bool
+	// Getter is true for methods declared without a parameter list, which run when their property is read
+	Getter bool
+	// Setter is true for methods declared with a leading 'set', which run when their property is assigned
+	Setter bool
 }
 
 func (f *FunctionDeclaration) Accept(v Visitor) error {
 	return v.VisitFunctionDeclaration(f)
 }
 
+// Default returns the default value of the parameter at idx, or nil when it has none
+func (f *FunctionDeclaration) Default(idx int) Node {
+	if idx >= len(f.Defaults) {
+		return nil
+	}
+	return f.Defaults[idx]
+}
+
 type CallExpr struct {
+	Pos
 	Callee Node
 	Paren  *lexer.Token // for debugging + reporting
 	Args   []Node
+	// ArgNames are the names of the named arguments in Args, and nil for positional arguments. ArgNames itself is
+	// nil when no argument is named.
+	ArgNames []*lexer.Token
 }
 
 func (f *CallExpr) Accept(v Visitor) error {
@@ -84,6 +133,7 @@ func (f *CallExpr) Accept(v Visitor) error {
 }
 
 type WhileStmt struct {
+	Pos
 	Expression Node
 	Body       Node
 }
@@ -92,7 +142,21 @@ func (w *WhileStmt) Accept(v Visitor) error {
 	return v.VisitWhileStmt(w)
 }
 
+// ForInStmt is a loop over each value of an iterable, which declares Variable afresh on each iteration to hold
+// the value
+type ForInStmt struct {
+	Pos
+	Variable *VarStmt
+	Iterable Node
+	Body     Node
+}
+
+func (f *ForInStmt) Accept(v Visitor) error {
+	return v.VisitForInStmt(f)
+}
+
 type LogicalConjuction struct {
+	Pos
 	Left  Node
 	And   bool
 	Right Node
@@ -103,6 +167,7 @@ func (c *LogicalConjuction) Accept(v Visitor) error {
 }
 
 type IfStmt struct {
+	Pos
 	Expression    Node
 	Statement     Node
 	ElseStatement Node
@@ -113,6 +178,7 @@ func (i *IfStmt) Accept(v Visitor) error {
 }
 
 type Block struct {
+	Pos
 	Statements []Node
 }
 
@@ -121,6 +187,7 @@ func (b *Block) Accept(v Visitor) error {
 }
 
 type Binary struct {
+	Pos
 	Left     Node
 	Right    Node
 	Operator *lexer.Token
@@ -131,6 +198,7 @@ func (b *Binary) Accept(v Visitor) error {
 }
 
 type Grouping struct {
+	Pos
 	Expression Node
 }
 
@@ -139,6 +207,7 @@ func (g *Grouping) Accept(v Visitor) error {
 }
 
 type Literal struct {
+	Pos
 	Value any // Probably make a union type here
 }
 
@@ -147,6 +216,7 @@ func (l *Literal) Accept(v Visitor) error {
 }
 
 type Unary struct {
+	Pos
 	Operator *lexer.Token
 	Right    Node
 }
@@ -156,6 +226,7 @@ func (u *Unary) Accept(v Visitor) error {
 }
 
 type Variable struct {
+	Pos
 	TokenName string
 }
 
@@ -164,6 +235,7 @@ func (v *Variable) Accept(visitor Visitor) error {
 }
 
 type Assignment struct {
+	Pos
 	TokenName string
 	Value     Node
 }
@@ -173,12 +245,32 @@ func (a *Assignment) Accept(visitor Visitor) error {
 }
 
 // Node represents a node in the AST. All nodes must implement the Accept method
-// which allows the node to be visited by a Visitor.
+// which allows the node to be visited by a Visitor, and report the position in the
+// source they were parsed from.
 type Node interface {
 	Accept(Visitor) error
+	Position() lexer.Position
+}
+
+// Pos is embedded into every node and records where in the source the node was parsed from.
+// Declarations are positioned at their name, other nodes at their leading keyword or operator.
+type Pos lexer.Position
+
+// Position returns the location of the node in its source
+func (p Pos) Position() lexer.Position {
+	return lexer.Position(p)
+}
+
+// tokenPos returns the Pos of a token, or an unknown Pos if there is no token
+func tokenPos(t *lexer.Token) Pos {
+	if t == nil {
+		return Pos{}
+	}
+	return Pos(t.Position())
 }
 
 type PrintStmt struct {
+	Pos
 	Arg Node
 }
 
@@ -187,6 +279,7 @@ func (p *PrintStmt) Accept(v Visitor) error {
 }
 
 type VarStmt struct {
+	Pos
 	Name        string
 	Initializer Node
 }
@@ -210,9 +303,11 @@ type Visitor interface {
 	VisitAssignment(v *Assignment) error
 	VisitLogicalConjunction(v *LogicalConjuction) error
 	VisitWhileStmt(w *WhileStmt) error
+	VisitForInStmt(f *ForInStmt) error
 	VisitCallExpr(f *CallExpr) error
 	VisitFunctionDeclaration(f *FunctionDeclaration) error
 	VisitReturnStmt(r *ReturnStmt) error
+	VisitYieldStmt(y *YieldStmt) error
 	VisitClassDeclaration(c *ClassDeclaration) error
 	VisitGetExpr(g *GetExpr) error
 	VisitSetExpr(s *SetExpr) error
@@ -224,9 +319,47 @@ type LoxInterpreter interface {
 	Evaluate(Node) (domain.Value, error)
 	ExecuteBlock(*Block, *environment.Environment) error
 	GetEnvironment() *environment.Environment
+	// Stringify returns the text a value is printed as
+	Stringify(domain.Value) (string, error)
+	// Context returns the context of the current run, which is cancelled when the run is aborted
+	Context() context.Context
+	// AllocateString accounts for a string of the given length which is about to be created, failing once the
+	// memory limit is exceeded
+	AllocateString(length int) error
+	// MemoryAvailable returns how many bytes of string may be created before the memory limit is exceeded, and
+	// false when there is no limit
+	MemoryAvailable() (int64, bool)
 }
 
 type LoxCallable interface {
-	Arity() int
+	Arity() Arity
 	Call(i LoxInterpreter, args []domain.Value) (domain.Value, error)
 }
+
+// Variadic is the Max of the Arity of a callable which accepts any number of arguments beyond its Min
+const Variadic = -1
+
+// Arity is the number of arguments a callable accepts, from Min up to Max
+type Arity struct {
+	Min, Max int
+}
+
+// ExactArity is the Arity of a callable which accepts exactly n arguments
+func ExactArity(n int) Arity {
+	return Arity{Min: n, Max: n}
+}
+
+// Accepts reports whether a callable can be called with n arguments
+func (a Arity) Accepts(n int) bool {
+	return n >= a.Min && (a.Max == Variadic || n <= a.Max)
+}
+
+func (a Arity) String() string {
+	switch {
+	case a.Max == Variadic:
+		return fmt.Sprintf("at least %d", a.Min)
+	case a.Min == a.Max:
+		return fmt.Sprintf("%d", a.Min)
+	}
+	return fmt.Sprintf("%d to %d", a.Min, a.Max)
+}
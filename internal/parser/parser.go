@@ -36,8 +36,13 @@ func (p *Parser) Parse() ([]Node, error) {
 	for !p.isAtEnd() {
 		stmt, err := p.declaration()
 		if err != nil {
+			// Record where parsing failed before synchronizing moves past it
+			posErr := &lexer.PositionError{Err: err}
+			if cur := p.getCurrent(); cur != nil {
+				posErr.Pos = cur.Position()
+			}
 			p.synchronize()
-			return nil, err // REPL only?
+			return nil, posErr // REPL only?
 		}
 
 		stmts = append(stmts, stmt)
@@ -48,11 +53,15 @@ func (p *Parser) Parse() ([]Node, error) {
 // declaration  → funDecl
 // | varDecl
 // | statement
-// | classDecl ;
+// | classDecl
+// | traitDecl ;
 func (p *Parser) declaration() (s Node, err error) {
 	if p.match(lexer.CLASS) {
 		return p.classDeclaration()
 	}
+	if p.match(lexer.TRAIT) {
+		return p.traitDeclaration()
+	}
 	if p.match(lexer.FUN) {
 		return p.funcDeclaration("function")
 	}
@@ -62,7 +71,7 @@ func (p *Parser) declaration() (s Node, err error) {
 	return p.statement()
 }
 
-// classDecl → "class" IDENTIFIER ( "<" IDENTIFIER )? "{" function* "}" ;
+// classDecl → "class" IDENTIFIER ( "<" IDENTIFIER )? traits? "{" method* "}" ;
 func (p *Parser) classDeclaration() (Node, error) {
 	name, err := p.consume(lexer.IDENTIFIER)
 	if err != nil {
@@ -75,7 +84,12 @@ func (p *Parser) classDeclaration() (Node, error) {
 		if err != nil {
 			return nil, fmt.Errorf("expected superclass name")
 		}
-		superClass = &Variable{TokenName: t.Lexeme}
+		superClass = &Variable{Pos: tokenPos(t), TokenName: t.Lexeme}
+	}
+
+	traits, err := p.traits()
+	if err != nil {
+		return nil, err
 	}
 
 	_, err = p.consume(lexer.LEFT_BRACE)
@@ -83,9 +97,72 @@ func (p *Parser) classDeclaration() (Node, error) {
 		return nil, fmt.Errorf("expected a '{' after class name; err=%w", err)
 	}
 
+	methods, err := p.classBody()
+	if err != nil {
+		return nil, err
+	}
+	return &ClassDeclaration{
+		Pos:        tokenPos(name),
+		Name:       name.Lexeme,
+		Methods:    methods,
+		SuperClass: superClass,
+		Traits:     traits,
+	}, nil
+}
+
+// traitDecl → "trait" IDENTIFIER traits? "{" method* "}" ;
+func (p *Parser) traitDeclaration() (Node, error) {
+	name, err := p.consume(lexer.IDENTIFIER)
+	if err != nil {
+		return nil, fmt.Errorf("expected trait name")
+	}
+
+	traits, err := p.traits()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = p.consume(lexer.LEFT_BRACE)
+	if err != nil {
+		return nil, fmt.Errorf("expected a '{' after trait name; err=%w", err)
+	}
+
+	methods, err := p.classBody()
+	if err != nil {
+		return nil, err
+	}
+	return &ClassDeclaration{
+		Pos:     tokenPos(name),
+		Name:    name.Lexeme,
+		Methods: methods,
+		Traits:  traits,
+		Trait:   true,
+	}, nil
+}
+
+// traits → "with" IDENTIFIER ( "," IDENTIFIER )* ;
+func (p *Parser) traits() ([]*Variable, error) {
+	if !p.match(lexer.WITH) {
+		return nil, nil
+	}
+	var traits []*Variable
+	for {
+		t, err := p.consume(lexer.IDENTIFIER)
+		if err != nil {
+			return nil, fmt.Errorf("expected trait name after 'with'")
+		}
+		traits = append(traits, &Variable{Pos: tokenPos(t), TokenName: t.Lexeme})
+		if !p.match(lexer.COMMA) {
+			return traits, nil
+		}
+	}
+}
+
+// classBody parses the methods of a class or trait up to and including its closing '}'
+func (p *Parser) classBody() ([]Node, error) {
 	var methods []Node
 	for !p.peekMatch(lexer.RIGHT_BRACE) && !p.isAtEnd() {
-		m, err := p.funcDeclaration("method")
+		m, err := p.method()
 		if err != nil {
 			return nil, fmt.Errorf("error with function declaration within class; err=%w", err)
 		}
@@ -96,15 +173,43 @@ func (p *Parser) classDeclaration() (Node, error) {
 		return nil, fmt.Errorf("expected a '}' after class declaration")
 	}
 
-	_, err = p.consume(lexer.RIGHT_BRACE)
+	_, err := p.consume(lexer.RIGHT_BRACE)
 	if err != nil {
 		return nil, fmt.Errorf("expected a '}' after a class body")
 	}
-	return &ClassDeclaration{
-		Name:       name.Lexeme,
-		Methods:    methods,
-		SuperClass: superClass,
-	}, nil
+	return methods, nil
+}
+
+// method → "class"? function
+// | "class"? IDENTIFIER block
+// | "set" function ;
+func (p *Parser) method() (*FunctionDeclaration, error) {
+	static := p.match(lexer.CLASS)
+	// 'set' is only special before the name of a setter, so it can still be the name of a method
+	setter := false
+	if cur := p.getCurrent(); cur != nil && cur.Type == lexer.IDENTIFIER && cur.Lexeme == "set" &&
+		p.current+1 < len(p.tokens) && p.tokens[p.current+1].Type == lexer.IDENTIFIER {
+		_ = p.advance()
+		setter = true
+	}
+
+	m, err := p.funcDeclaration("method")
+	if err != nil {
+		return nil, err
+	}
+	f := m.(*FunctionDeclaration)
+	f.Static = static
+	f.Setter = setter
+
+	switch {
+	case setter && static:
+		return nil, fmt.Errorf("class method '%s' can't be a setter", f.Name)
+	case setter && (f.Getter || len(f.Params) != 1 || f.Rest):
+		return nil, fmt.Errorf("setter '%s' must have exactly one parameter", f.Name)
+	case f.Getter && f.Name == "init" && !static:
+		return nil, errors.New("an initializer must have a parameter list")
+	}
+	return f, nil
 }
 
 // function       → IDENTIFIER "(" parameters? ")" block ;
@@ -114,32 +219,28 @@ func (p *Parser) funcDeclaration(kind string) (s Node, err error) {
 		return nil, fmt.Errorf("expected %s name", kind)
 	}
 
+	// A method without a parameter list is a getter, which runs when its property is read
+	if kind == "method" && p.match(lexer.LEFT_BRACE) {
+		body, err := p.block()
+		if err != nil {
+			return nil, err
+		}
+		return &FunctionDeclaration{
+			Pos:    tokenPos(name),
+			Name:   name.Lexeme,
+			Body:   body.(*Block).Statements,
+			Getter: true,
+		}, nil
+	}
+
 	_, err = p.consume(lexer.LEFT_PAREN)
 	if err != nil {
 		return nil, fmt.Errorf("expected a '(' after function identifier; err=%w", err)
 	}
 
-	var params []string
-	if !p.match(lexer.RIGHT_PAREN) {
-		for {
-			if len(params) >= 255 {
-				return nil, errors.New("can't have more than 255 parameters")
-			}
-
-			param, paramErr := p.consume(lexer.IDENTIFIER)
-			if paramErr != nil {
-				return nil, paramErr
-			}
-			params = append(params, param.Lexeme)
-			if p.match(lexer.COMMA) {
-				continue
-			}
-			if p.match(lexer.RIGHT_PAREN) {
-				break
-			} else {
-				return nil, errors.New("Expected closing ')' after parameter list")
-			}
-		}
+	params, defaults, rest, err := p.parameters()
+	if err != nil {
+		return nil, err
 	}
 
 	_, err = p.consume(lexer.LEFT_BRACE)
@@ -157,12 +258,70 @@ func (p *Parser) funcDeclaration(kind string) (s Node, err error) {
 	}
 
 	return &FunctionDeclaration{
-		Name:   name.Lexeme,
-		Params: params,
-		Body:   bodyInf.Statements,
+		Pos:      tokenPos(name),
+		Name:     name.Lexeme,
+		Params:   params,
+		Defaults: defaults,
+		Rest:     rest,
+		Body:     bodyInf.Statements,
 	}, nil
 }
 
+// parameters → parameter ( "," parameter )* ( "," "..." IDENTIFIER )? ")"
+// | "..." IDENTIFIER ")"
+// | ")" ;
+// parameter  → IDENTIFIER ( "=" expression )? ;
+//
+// parameters parses a parameter list after its opening '(', returning the default value of each parameter, or nil
+// defaults when none have one. Parameters with defaults must follow those without, as arguments are matched to
+// parameters in order.
+func (p *Parser) parameters() (params []*lexer.Token, defaults []Node, rest bool, err error) {
+	if p.match(lexer.RIGHT_PAREN) {
+		return nil, nil, false, nil
+	}
+	hasDefaults := false
+	for {
+		if len(params) >= 255 {
+			return nil, nil, false, errors.New("can't have more than 255 parameters")
+		}
+		if rest {
+			return nil, nil, false, fmt.Errorf("rest parameter '%s' must be the last parameter", params[len(params)-1].Lexeme)
+		}
+		rest = p.match(lexer.ELLIPSIS)
+
+		param, paramErr := p.consume(lexer.IDENTIFIER)
+		if paramErr != nil {
+			return nil, nil, false, paramErr
+		}
+		var def Node
+		switch {
+		case p.match(lexer.EQUAL):
+			if rest {
+				return nil, nil, false, fmt.Errorf("rest parameter '%s' can't have a default value", param.Lexeme)
+			}
+			if def, err = p.expressionStmt(); err != nil {
+				return nil, nil, false, err
+			}
+			hasDefaults = true
+		case hasDefaults && !rest:
+			return nil, nil, false, fmt.Errorf("parameter '%s' without a default value can't follow one with a default", param.Lexeme)
+		}
+		params = append(params, param)
+		defaults = append(defaults, def)
+
+		if p.match(lexer.COMMA) {
+			continue
+		}
+		if !p.match(lexer.RIGHT_PAREN) {
+			return nil, nil, false, errors.New("Expected closing ')' after parameter list")
+		}
+		if !hasDefaults {
+			defaults = nil
+		}
+		return params, defaults, rest, nil
+	}
+}
+
 func (p *Parser) varDeclaration() (s Node, err error) {
 	name, err := p.consume(lexer.IDENTIFIER)
 	if err != nil {
@@ -183,6 +342,7 @@ func (p *Parser) varDeclaration() (s Node, err error) {
 	}
 
 	return &VarStmt{
+		Pos:         tokenPos(name),
 		Name:        name.Lexeme,
 		Initializer: initializer,
 	}, nil
@@ -193,6 +353,7 @@ func (p *Parser) varDeclaration() (s Node, err error) {
 // | ifStmt
 // | printStmt
 // | returnStmt
+// | yieldStmt
 // | whileStmt
 // | block
 func (p *Parser) statement() (s Node, err error) {
@@ -209,13 +370,16 @@ func (p *Parser) statement() (s Node, err error) {
 		if err != nil {
 			return
 		}
-		s = &PrintStmt{Arg: arg}
+		s = &PrintStmt{Pos: tokenPos(startToken), Arg: arg}
 	case lexer.WHILE:
 		_ = p.advance()
 		return p.whileStatement()
 	case lexer.RETURN:
 		_ = p.advance()
 		s, err = p.returnStatement()
+	case lexer.YIELD:
+		_ = p.advance()
+		s, err = p.yieldStatement()
 	case lexer.FOR:
 		_ = p.advance()
 		return p.forStatement()
@@ -229,7 +393,7 @@ func (p *Parser) statement() (s Node, err error) {
 		}
 	}
 
-	if err == nil && !p.match(lexer.SEMICOLON) { // exprStmt, print + return expect semi-colons
+	if err == nil && !p.match(lexer.SEMICOLON) { // exprStmt, print, return + yield expect semi-colons
 		return nil, startToken.GenerateTokenError("Expected ; after Statement")
 	}
 	return
@@ -237,13 +401,22 @@ func (p *Parser) statement() (s Node, err error) {
 
 // forStmt    → "for" "(" ( varDecl | exprStmt | ";" )
 // expression? ";"
-// expression? ")" statement ;
+// expression? ")" statement
+// | "for" "(" "var" IDENTIFIER "in" expression ")" statement ;
 func (p *Parser) forStatement() (Node, error) {
 	var err error
+	pos := tokenPos(p.getPrevious())
 	if _, err = p.consume(lexer.LEFT_PAREN); err != nil {
 		return nil, err
 	}
 
+	// 'in' is only special after the variable of a for-in loop, so it can still be used as a name
+	if p.current+2 < len(p.tokens) && p.tokens[p.current].Type == lexer.VAR &&
+		p.tokens[p.current+1].Type == lexer.IDENTIFIER && p.tokens[p.current+2].Type == lexer.IDENTIFIER &&
+		p.tokens[p.current+2].Lexeme == "in" {
+		return p.forInStatement(pos)
+	}
+
 	var initializer Node
 	if !p.match(lexer.SEMICOLON) {
 		if p.match(lexer.VAR) {
@@ -285,16 +458,17 @@ func (p *Parser) forStatement() (Node, error) {
 	}
 
 	if increment != nil {
-		body = &Block{Statements: []Node{
+		body = &Block{Pos: pos, Statements: []Node{
 			body,
 			increment,
 		}}
 	}
 
 	if condition == nil {
-		condition = &Literal{Value: true}
+		condition = &Literal{Pos: pos, Value: true}
 	}
 	loop := &WhileStmt{
+		Pos:        pos,
 		Body:       body,
 		Expression: condition,
 	}
@@ -303,12 +477,38 @@ func (p *Parser) forStatement() (Node, error) {
 		return loop, nil
 	}
 
-	return &Block{Statements: []Node{initializer, loop}}, nil
+	return &Block{Pos: pos, Statements: []Node{initializer, loop}}, nil
+}
+
+// forInStatement parses the rest of a for-in loop, from its "var"
+func (p *Parser) forInStatement(pos Pos) (Node, error) {
+	name := p.tokens[p.current+1]
+	p.current += 3 // "var", the name and "in"
+
+	iterable, err := p.expressionStmt()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = p.consume(lexer.RIGHT_PAREN); err != nil {
+		return nil, err
+	}
+
+	body, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+	return &ForInStmt{
+		Pos:      pos,
+		Variable: &VarStmt{Pos: tokenPos(name), Name: name.Lexeme},
+		Iterable: iterable,
+		Body:     body,
+	}, nil
 }
 
 // whileStmt → "while" "(" expression ")" statement ;
 func (p *Parser) whileStatement() (Node, error) {
 	var err error
+	pos := tokenPos(p.getPrevious())
 	if _, err = p.consume(lexer.LEFT_PAREN); err != nil {
 		return nil, err
 	}
@@ -328,6 +528,7 @@ func (p *Parser) whileStatement() (Node, error) {
 	}
 
 	return &WhileStmt{
+		Pos:        pos,
 		Expression: expr,
 		Body:       body,
 	}, nil
@@ -335,21 +536,36 @@ func (p *Parser) whileStatement() (Node, error) {
 
 // returnStmt → "return" expression? ";"
 func (p *Parser) returnStatement() (Node, error) {
+	pos := tokenPos(p.getPrevious())
 	if p.peekMatch(lexer.SEMICOLON) {
-		return &ReturnStmt{}, nil
+		return &ReturnStmt{Pos: pos}, nil
 	}
 	expr, err := p.expressionStmt()
 	if err != nil {
 		return nil, err
 	}
 
-	return &ReturnStmt{Expression: expr}, nil
+	return &ReturnStmt{Pos: pos, Expression: expr}, nil
+}
+
+// yieldStmt → "yield" expression? ";"
+func (p *Parser) yieldStatement() (Node, error) {
+	pos := tokenPos(p.getPrevious())
+	if p.peekMatch(lexer.SEMICOLON) {
+		return &YieldStmt{Pos: pos}, nil
+	}
+	expr, err := p.expressionStmt()
+	if err != nil {
+		return nil, err
+	}
+
+	return &YieldStmt{Pos: pos, Expression: expr}, nil
 }
 
 // ifStmt → "if" "(" expressionStmt ")" statement ( "else" statement )? ;
 func (p *Parser) ifStatement() (Node, error) {
 	var err error
-	ifStmt := &IfStmt{}
+	ifStmt := &IfStmt{Pos: tokenPos(p.getPrevious())}
 	if !p.match(lexer.LEFT_PAREN) {
 		return nil, p.getPrevious().GenerateTokenError("Expected open paren after 'if' Statement")
 	}
@@ -392,7 +608,7 @@ func (p *Parser) block() (Node, error) {
 		nodes = append(nodes, n)
 	}
 
-	return &Block{Statements: nodes}, nil
+	return &Block{Pos: tokenPos(open), Statements: nodes}, nil
 }
 
 // expressionStmt -> assignment
@@ -421,6 +637,7 @@ func (p *Parser) assignment() (Node, error) {
 
 	if v, ok := expr.(*Variable); ok {
 		return &Assignment{
+			Pos:       v.Pos,
 			TokenName: v.TokenName,
 			Value:     rhs,
 		}, nil
@@ -428,6 +645,7 @@ func (p *Parser) assignment() (Node, error) {
 
 	if g, ok := expr.(*GetExpr); ok {
 		return &SetExpr{
+			Pos:      g.Pos,
 			Instance: g.Instance,
 			Name:     g.Name,
 			Value:    rhs,
@@ -450,6 +668,7 @@ func (p *Parser) logicalConjunction() (Node, error) {
 	}
 
 	conj := &LogicalConjuction{
+		Pos:  tokenPos(p.getPrevious()),
 		Left: left,
 		And:  p.getPrevious().Type == lexer.AND,
 	}
@@ -478,6 +697,7 @@ func (p *Parser) equality() (Node, error) {
 			return nil, err
 		}
 		res = &Binary{
+			Pos:      tokenPos(cur),
 			Left:     res,
 			Right:    right,
 			Operator: cur,
@@ -487,7 +707,7 @@ func (p *Parser) equality() (Node, error) {
 	return res, nil
 }
 
-// comparison → term ( ( ">" | ">=" | "<" | "<=" ) term )* ;
+// comparison → term ( ( ">" | ">=" | "<" | "<=" | "instanceof" ) term )* ;
 func (p *Parser) comparison() (Node, error) {
 	res, err := p.term()
 	if err != nil {
@@ -496,12 +716,13 @@ func (p *Parser) comparison() (Node, error) {
 	if p.isAtEnd() {
 		return res, nil
 	}
-	for cur := p.tokens[p.current]; p.match(lexer.LESS, lexer.LESS_EQUAL, lexer.GREATER, lexer.GREATER_EQUAL); {
+	for cur := p.tokens[p.current]; p.match(lexer.LESS, lexer.LESS_EQUAL, lexer.GREATER, lexer.GREATER_EQUAL, lexer.INSTANCEOF); {
 		right, err := p.term()
 		if err != nil {
 			return nil, err
 		}
 		res = &Binary{
+			Pos:      tokenPos(cur),
 			Left:     res,
 			Right:    right,
 			Operator: cur,
@@ -525,6 +746,7 @@ func (p *Parser) term() (Node, error) {
 			return nil, err
 		}
 		res = &Binary{
+			Pos:      tokenPos(cur),
 			Left:     res,
 			Right:    right,
 			Operator: cur,
@@ -548,6 +770,7 @@ func (p *Parser) factor() (Node, error) {
 			return nil, err
 		}
 		res = &Binary{
+			Pos:      tokenPos(cur),
 			Left:     res,
 			Right:    right,
 			Operator: cur,
@@ -559,11 +782,12 @@ func (p *Parser) factor() (Node, error) {
 // unary → ( "!" | "-" ) unary | call;
 func (p *Parser) unary() (Node, error) {
 	if cur := p.tokens[p.current]; p.match(lexer.BANG, lexer.MINUS) {
-		right, err := p.primary()
+		right, err := p.unary()
 		if err != nil {
 			return nil, err
 		}
 		return &Unary{
+			Pos:      tokenPos(cur),
 			Operator: cur,
 			Right:    right,
 		}, nil
@@ -590,7 +814,7 @@ func (p *Parser) call() (Node, error) {
 			if err != nil {
 				return nil, fmt.Errorf("expected identifier after '.' in call expression; err=%w", err)
 			}
-			expr = &GetExpr{Instance: expr, Name: name}
+			expr = &GetExpr{Pos: tokenPos(name), Instance: expr, Name: name}
 		} else { // Otherwise, we've reached the end of the call expression
 			return expr, nil
 		}
@@ -599,18 +823,39 @@ func (p *Parser) call() (Node, error) {
 
 // finishCall will finish parsing a function call expression, passed in a callee, which
 // should be a primary expression (e.g. IDENTIFIER, NUMBER, STRING, etc.)
+//
+// arguments → argument ( "," argument )* ;
+// argument  → ( IDENTIFIER ":" )? expression ;
 func (p *Parser) finishCall(callee Node) (Node, error) {
 	var args []Node
+	var names []*lexer.Token
+	named := map[string]bool{}
 	if !p.match(lexer.RIGHT_PAREN) {
 		for {
 			if len(args) >= 255 {
 				return nil, p.tokens[p.current].GenerateTokenError("Can't have more than 255 arguments.")
 			}
+			// A named argument is an identifier followed by a ':', and positional arguments can't follow one
+			var name *lexer.Token
+			if cur := p.getCurrent(); cur.Type == lexer.IDENTIFIER && p.current+1 < len(p.tokens) &&
+				p.tokens[p.current+1].Type == lexer.COLON {
+				name = cur
+				if named[name.Lexeme] {
+					return nil, name.GenerateTokenError(fmt.Sprintf("Argument '%s' is named more than once", name.Lexeme))
+				}
+				named[name.Lexeme] = true
+				_ = p.advance()
+				_ = p.advance()
+			} else if len(named) > 0 {
+				return nil, cur.GenerateTokenError("Positional arguments can't follow named arguments")
+			}
+
 			arg, err := p.expressionStmt()
 			if err != nil {
 				return nil, err
 			}
 			args = append(args, arg)
+			names = append(names, name)
 			if !p.match(lexer.COMMA) {
 				if !p.match(lexer.RIGHT_PAREN) {
 					return nil, p.tokens[p.current].GenerateTokenError("Expected closing parenthesis after arg list in function call")
@@ -619,11 +864,16 @@ func (p *Parser) finishCall(callee Node) (Node, error) {
 			}
 		}
 	}
+	if len(named) == 0 {
+		names = nil
+	}
 
 	return &CallExpr{
-		Callee: callee,
-		Args:   args,
-		Paren:  p.getCurrent(),
+		Pos:      Pos(callee.Position()),
+		Callee:   callee,
+		Args:     args,
+		ArgNames: names,
+		Paren:    p.getCurrent(),
 	}, nil
 }
 
@@ -643,25 +893,25 @@ func (p *Parser) primary() (Node, error) {
 		if !p.match(lexer.RIGHT_PAREN) {
 			return nil, cur.GenerateTokenError("unexpected token, expected ')'")
 		}
-		return &Grouping{inner}, nil
+		return &Grouping{Pos: tokenPos(cur), Expression: inner}, nil
 	}
 
 	_ = p.advance()
 	switch cur.Type {
 	case lexer.NUMBER, lexer.STRING:
-		return &Literal{Value: cur.Literal}, nil
+		return &Literal{Pos: tokenPos(cur), Value: cur.Literal}, nil
 
 	case lexer.TRUE:
-		return &Literal{Value: true}, nil
+		return &Literal{Pos: tokenPos(cur), Value: true}, nil
 	case lexer.FALSE:
-		return &Literal{Value: false}, nil
+		return &Literal{Pos: tokenPos(cur), Value: false}, nil
 	case lexer.NIL:
-		return &Literal{Value: nil}, nil
+		return &Literal{Pos: tokenPos(cur), Value: nil}, nil
 
 	case lexer.THIS:
-		return &ThisExpr{Keyword: cur}, nil
+		return &ThisExpr{Pos: tokenPos(cur), Keyword: cur}, nil
 	case lexer.SUPER:
-		s := &SuperExpr{Keyword: cur}
+		s := &SuperExpr{Pos: tokenPos(cur), Keyword: cur}
 		if !p.match(lexer.DOT) {
 			return nil, cur.GenerateTokenError("Expected '.' after 'super'")
 		}
@@ -673,7 +923,7 @@ func (p *Parser) primary() (Node, error) {
 		return s, nil
 
 	case lexer.IDENTIFIER:
-		return &Variable{TokenName: cur.Lexeme}, nil
+		return &Variable{Pos: tokenPos(cur), TokenName: cur.Lexeme}, nil
 
 	default:
 		return nil, cur.GenerateTokenError("Could not parse Expression, expected a primary Expression")
@@ -770,7 +1020,7 @@ func (p *Parser) synchronize() {
 		}
 
 		switch p.getCurrent().Type {
-		case lexer.CLASS, lexer.FUN, lexer.VAR, lexer.FOR, lexer.IF, lexer.WHILE, lexer.PRINT, lexer.RETURN:
+		case lexer.CLASS, lexer.TRAIT, lexer.FUN, lexer.VAR, lexer.FOR, lexer.IF, lexer.WHILE, lexer.PRINT, lexer.RETURN:
 			return
 		}
 	}
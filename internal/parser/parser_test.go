@@ -105,7 +105,7 @@ func TestRawTokensSuccess(t *testing.T) {
 					Lexeme: "true",
 				},
 			},
-			expectedOutput: "(+ (+ (group (!= 1 (<= (<= 2 3) 4))) 43) (* hehehe true))",
+			expectedOutput: "(+ (+ (group (!= 1 (<= (<= 2 3) 4))) 43) (* \"hehehe\" true))",
 		},
 	}
 	printer := ExprPrinter{}
@@ -132,11 +132,11 @@ func TestScannedTokensSuccess(t *testing.T) {
 		},
 		{
 			inputExpression: `"hehehe"`,
-			expectedOutput:  `hehehe`,
+			expectedOutput:  `"hehehe"`,
 		},
 		{
 			inputExpression: `"hehehe" + 42`,
-			expectedOutput:  `(+ hehehe 42)`,
+			expectedOutput:  `(+ "hehehe" 42)`,
 		},
 		{
 			inputExpression: `4 / 5`,
@@ -148,7 +148,15 @@ func TestScannedTokensSuccess(t *testing.T) {
 		},
 		{
 			inputExpression: `(1 +1) * 43 - "hehehe" * true`,
-			expectedOutput:  `(- (* (group (+ 1 1)) 43) (* hehehe true))`,
+			expectedOutput:  `(- (* (group (+ 1 1)) 43) (* "hehehe" true))`,
+		},
+		{
+			inputExpression: `!!x - -a.b`,
+			expectedOutput:  `(- (! (! x)) (- (get a b)))`,
+		},
+		{
+			inputExpression: `-f(1) * !g()`,
+			expectedOutput:  `(* (- (call f 1)) (! (call g)))`,
 		},
 	}
 
@@ -164,3 +172,25 @@ func TestScannedTokensSuccess(t *testing.T) {
 		assert.Equal(t, test.expectedOutput, printer.Print(res), "Failed test with input Expression %s", test.inputExpression)
 	}
 }
+
+func TestArity(t *testing.T) {
+	cases := []struct {
+		arity    Arity
+		accepts  []int
+		rejects  []int
+		expected string
+	}{
+		{ExactArity(2), []int{2}, []int{1, 3}, "2"},
+		{Arity{Min: 1, Max: 3}, []int{1, 2, 3}, []int{0, 4}, "1 to 3"},
+		{Arity{Min: 1, Max: Variadic}, []int{1, 2, 100}, []int{0}, "at least 1"},
+	}
+	for _, c := range cases {
+		for _, n := range c.accepts {
+			assert.True(t, c.arity.Accepts(n), "%s should accept %d", c.arity, n)
+		}
+		for _, n := range c.rejects {
+			assert.False(t, c.arity.Accepts(n), "%s should reject %d", c.arity, n)
+		}
+		assert.Equal(t, c.expected, c.arity.String())
+	}
+}
@@ -0,0 +1,181 @@
+package profiler
+
+import (
+	"compress/gzip"
+	"io"
+	"sort"
+)
+
+// Field numbers of the messages in pprof's profile.proto, see
+// https://github.com/google/pprof/blob/main/proto/profile.proto
+const (
+	profileSampleType    = 1
+	profileSample        = 2
+	profileLocation      = 4
+	profileFunction      = 5
+	profileStringTable   = 6
+	profileTimeNanos     = 9
+	profileDurationNanos = 10
+	profilePeriodType    = 11
+	profilePeriod        = 12
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+
+	locationID   = 1
+	locationLine = 4
+
+	lineFunctionID = 1
+	lineLine       = 2
+
+	functionID         = 1
+	functionName       = 2
+	functionSystemName = 3
+	functionFilename   = 4
+	functionStartLine  = 5
+)
+
+// WritePprof writes the profile in the gzipped protocol buffer format read by `go tool pprof`. Each Lox function
+// is a location, at the line it was declared on, and each call stack is a sample with the number of calls and the
+// time spent in the innermost function of the stack.
+func (p *Profiler) WritePprof(w io.Writer) error {
+	b := &pprofBuilder{strings: map[string]int{"": 0}, stringTable: []string{""}, functionIDs: map[functionKey]uint64{}}
+
+	b.message(profileSampleType, func() {
+		b.varint(valueTypeType, b.str("calls"))
+		b.varint(valueTypeUnit, b.str("count"))
+	})
+	b.message(profileSampleType, func() {
+		b.varint(valueTypeType, b.str("wall"))
+		b.varint(valueTypeUnit, b.str("nanoseconds"))
+	})
+
+	// Functions are added in order of label so the output is deterministic. Their names are labels, which include
+	// where they were declared, as pprof merges functions of the same name.
+	functions := p.Functions()
+	sort.Slice(functions, func(i, j int) bool { return functions[i].Label() < functions[j].Label() })
+	for idx, fn := range functions {
+		id := uint64(idx + 1)
+		b.functionIDs[functionKey{name: fn.Name, declaration: fn.Declaration}] = id
+		b.message(profileFunction, func() {
+			b.varint(functionID, id)
+			b.varint(functionName, b.str(fn.Label()))
+			b.varint(functionSystemName, b.str(fn.Label()))
+			b.varint(functionFilename, b.str(p.filename))
+			b.varint(functionStartLine, uint64(fn.Declaration.Line))
+		})
+		// Each function has a single location, sharing its ID
+		b.message(profileLocation, func() {
+			b.varint(locationID, id)
+			b.message(locationLine, func() {
+				b.varint(lineFunctionID, id)
+				b.varint(lineLine, uint64(fn.Declaration.Line))
+			})
+		})
+	}
+
+	b.samples(p.stacks, nil)
+
+	b.varint(profileTimeNanos, uint64(p.start.UnixNano()))
+	b.varint(profileDurationNanos, uint64(p.duration.Nanoseconds()))
+	b.message(profilePeriodType, func() {
+		b.varint(valueTypeType, b.str("wall"))
+		b.varint(valueTypeUnit, b.str("nanoseconds"))
+	})
+	b.varint(profilePeriod, 1)
+
+	// The string table is written last, as strings are added to it while writing the other messages
+	for _, s := range b.stringTable {
+		b.bytes(profileStringTable, []byte(s))
+	}
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(b.buf); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// pprofBuilder encodes a pprof profile, implementing just enough of the protocol buffer wire format to do so
+type pprofBuilder struct {
+	buf         []byte
+	strings     map[string]int
+	stringTable []string
+	functionIDs map[functionKey]uint64
+}
+
+// samples adds a sample for node and each stack below it, where locations are the IDs of the node's callers from
+// the innermost outwards
+func (b *pprofBuilder) samples(node *stackNode, locations []uint64) {
+	if node.parent != nil {
+		locations = append([]uint64{b.functionIDs[node.fn]}, locations...)
+		b.message(profileSample, func() {
+			b.packed(sampleLocationID, locations)
+			b.packed(sampleValue, []uint64{uint64(node.calls), uint64(node.flat.Nanoseconds())})
+		})
+	}
+
+	children := make([]*stackNode, 0, len(node.children))
+	for _, child := range node.children {
+		children = append(children, child)
+	}
+	sort.Slice(children, func(i, j int) bool { return b.functionIDs[children[i].fn] < b.functionIDs[children[j].fn] })
+	for _, child := range children {
+		b.samples(child, locations)
+	}
+}
+
+// str returns the index of s in the string table, adding it if needed
+func (b *pprofBuilder) str(s string) uint64 {
+	idx, exists := b.strings[s]
+	if !exists {
+		idx = len(b.stringTable)
+		b.strings[s] = idx
+		b.stringTable = append(b.stringTable, s)
+	}
+	return uint64(idx)
+}
+
+func (b *pprofBuilder) tag(field int, wireType byte) {
+	b.rawVarint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (b *pprofBuilder) rawVarint(v uint64) {
+	for v >= 0x80 {
+		b.buf = append(b.buf, byte(v)|0x80)
+		v >>= 7
+	}
+	b.buf = append(b.buf, byte(v))
+}
+
+func (b *pprofBuilder) varint(field int, v uint64) {
+	b.tag(field, 0)
+	b.rawVarint(v)
+}
+
+func (b *pprofBuilder) bytes(field int, data []byte) {
+	b.tag(field, 2)
+	b.rawVarint(uint64(len(data)))
+	b.buf = append(b.buf, data...)
+}
+
+func (b *pprofBuilder) packed(field int, values []uint64) {
+	inner := &pprofBuilder{}
+	for _, v := range values {
+		inner.rawVarint(v)
+	}
+	b.bytes(field, inner.buf)
+}
+
+// message encodes a nested message, written by the given function
+func (b *pprofBuilder) message(field int, write func()) {
+	outer := b.buf
+	b.buf = nil
+	write()
+	inner := b.buf
+	b.buf = outer
+	b.bytes(field, inner)
+}
@@ -0,0 +1,194 @@
+// Package profiler attributes the wall time and calls of a Lox program to the functions and methods it calls.
+package profiler
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/levpaul/glocks/internal/domain"
+	"github.com/levpaul/glocks/internal/lexer"
+)
+
+// scriptName is the name time spent outside any function is attributed to. It isn't "<script>", as shown by the
+// call stack, because pprof removes anything between angle brackets from function names.
+const scriptName = "[script]"
+
+// FunctionStats is the profile of a single function
+type FunctionStats struct {
+	Name string
+	// Declaration is where the function was declared, which is unknown for classes and natives
+	Declaration lexer.Position
+	Calls       int
+	// Flat is the time spent within the function itself, excluding the functions it called
+	Flat time.Duration
+	// Cum is the time spent within the function including the functions it called. Recursive calls are only
+	// counted once, by the outermost call.
+	Cum time.Duration
+}
+
+// Label returns the name of the function followed by where it was declared, when that is known, which tells apart
+// functions of the same name such as nested helpers
+func (f FunctionStats) Label() string {
+	if f.Declaration == (lexer.Position{}) {
+		return f.Name
+	}
+	return fmt.Sprintf("%s:%d:%d", f.Name, f.Declaration.Line, f.Declaration.Column)
+}
+
+// functionKey identifies a function by its name and declaration, as functions declared in different places may
+// share a name
+type functionKey struct {
+	name        string
+	declaration lexer.Position
+}
+
+// stackNode is the profile of a single call stack, which becomes a sample of a pprof profile. Stacks form a tree,
+// where the children of a node are the stacks of calls made from it.
+type stackNode struct {
+	fn       functionKey
+	parent   *stackNode
+	children map[functionKey]*stackNode
+	calls    int
+	flat     time.Duration
+}
+
+// activeCall is a call which has started but not yet finished
+type activeCall struct {
+	start time.Time
+	// children is the time spent in calls made by this one
+	children time.Duration
+	// stack is the call stack this call is the innermost frame of
+	stack *stackNode
+}
+
+// Profiler is an interpreter.CallHook which measures the time spent in each function by timing each call. Every
+// call is measured, rather than sampling the call stack periodically, so call counts are exact and even short
+// programs have a complete profile.
+type Profiler struct {
+	// filename is the script being profiled, used for the locations of a pprof profile
+	filename  string
+	now       func() time.Time
+	start     time.Time
+	duration  time.Duration
+	active    []activeCall
+	functions map[functionKey]*FunctionStats
+	// stacks is the root of the tree of call stacks, which has no name itself
+	stacks *stackNode
+	// recursion is the number of active calls of each function, to avoid counting recursive calls' time twice
+	recursion map[functionKey]int
+}
+
+// New creates a Profiler for the script with the given file name
+func New(filename string) *Profiler {
+	return &Profiler{
+		filename:  filename,
+		now:       time.Now,
+		functions: map[functionKey]*FunctionStats{},
+		stacks:    &stackNode{children: map[functionKey]*stackNode{}},
+		recursion: map[functionKey]int{},
+	}
+}
+
+// Start begins profiling, attributing time to the top level of the script until a function is called
+func (p *Profiler) Start() {
+	p.start = p.now()
+	p.EnterCall(scriptName, lexer.Position{}, nil)
+}
+
+// Stop ends profiling, finishing any calls which are still active
+func (p *Profiler) Stop() {
+	for len(p.active) > 0 {
+		p.ExitCall(nil, nil)
+	}
+	p.duration = p.now().Sub(p.start)
+}
+
+// EnterCall implements interpreter.CallHook, starting the timing of a call
+func (p *Profiler) EnterCall(name string, declaration lexer.Position, _ []domain.Value) {
+	key := functionKey{name: name, declaration: declaration}
+	fn, exists := p.functions[key]
+	if !exists {
+		fn = &FunctionStats{Name: name, Declaration: declaration}
+		p.functions[key] = fn
+	}
+	fn.Calls++
+	p.recursion[key]++
+
+	caller := p.stacks
+	if len(p.active) > 0 {
+		caller = p.active[len(p.active)-1].stack
+	}
+	stack, exists := caller.children[key]
+	if !exists {
+		stack = &stackNode{fn: key, parent: caller, children: map[functionKey]*stackNode{}}
+		caller.children[key] = stack
+	}
+	stack.calls++
+
+	p.active = append(p.active, activeCall{start: p.now(), stack: stack})
+}
+
+// ExitCall implements interpreter.CallHook, finishing the timing of the innermost call
+func (p *Profiler) ExitCall(domain.Value, error) {
+	call := p.active[len(p.active)-1]
+	p.active = p.active[:len(p.active)-1]
+
+	key := call.stack.fn
+	elapsed := p.now().Sub(call.start)
+	flat := elapsed - call.children
+	p.functions[key].Flat += flat
+	call.stack.flat += flat
+	p.recursion[key]--
+	if p.recursion[key] == 0 {
+		p.functions[key].Cum += elapsed
+	}
+	if len(p.active) > 0 {
+		p.active[len(p.active)-1].children += elapsed
+	}
+}
+
+// Functions returns the profile of every function called, sorted by flat time, most first
+func (p *Profiler) Functions() []FunctionStats {
+	functions := make([]FunctionStats, 0, len(p.functions))
+	for _, fn := range p.functions {
+		functions = append(functions, *fn)
+	}
+	sort.Slice(functions, func(i, j int) bool {
+		if functions[i].Flat != functions[j].Flat {
+			return functions[i].Flat > functions[j].Flat
+		}
+		return functions[i].Label() < functions[j].Label()
+	})
+	return functions
+}
+
+// WriteReport writes a flat profile, sorted by the time spent in each function itself, followed by a cumulative
+// profile, sorted by the time spent in each function including the functions it called
+func (p *Profiler) WriteReport(w io.Writer) {
+	functions := p.Functions()
+	fmt.Fprintf(w, "Flat profile (total %s):\n", p.duration)
+	p.writeTable(w, functions)
+
+	sort.SliceStable(functions, func(i, j int) bool { return functions[i].Cum > functions[j].Cum })
+	fmt.Fprintf(w, "\nCumulative profile (total %s):\n", p.duration)
+	p.writeTable(w, functions)
+}
+
+func (p *Profiler) writeTable(w io.Writer, functions []FunctionStats) {
+	fmt.Fprintf(w, "%12s %7s %12s %7s %10s  %s\n", "flat", "flat%", "cum", "cum%", "calls", "function")
+	for _, fn := range functions {
+		fmt.Fprintf(w, "%12s %6.2f%% %12s %6.2f%% %10d  %s\n",
+			fn.Flat.Round(time.Microsecond), p.percent(fn.Flat),
+			fn.Cum.Round(time.Microsecond), p.percent(fn.Cum),
+			fn.Calls, fn.Label())
+	}
+}
+
+func (p *Profiler) percent(d time.Duration) float64 {
+	if p.duration == 0 {
+		return 0
+	}
+	return 100 * float64(d) / float64(p.duration)
+}
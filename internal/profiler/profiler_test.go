@@ -0,0 +1,127 @@
+package profiler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/levpaul/glocks/internal/interpreter"
+	"github.com/levpaul/glocks/internal/lexer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeClock returns a clock which advances by a millisecond each time it is read
+func fakeClock() func() time.Time {
+	now := time.Unix(0, 0)
+	return func() time.Time {
+		now = now.Add(time.Millisecond)
+		return now
+	}
+}
+
+func TestRecursiveCallsAreCountedOnce(t *testing.T) {
+	p := New("test.lox")
+	p.now = fakeClock()
+
+	p.Start()                                        // t=1, script entered at t=2
+	p.EnterCall("fib", lexer.Position{Line: 1}, nil) // t=3
+	p.EnterCall("fib", lexer.Position{Line: 1}, nil) // t=4
+	p.ExitCall(nil, nil)                             // t=5
+	p.ExitCall(nil, nil)                             // t=6
+	p.Stop()                                         // script exits at t=7, stops at t=8
+
+	functions := p.Functions()
+	require.Len(t, functions, 2)
+	assert.Equal(t, FunctionStats{Name: "[script]", Calls: 1, Flat: 2 * time.Millisecond, Cum: 5 * time.Millisecond}, functions[1])
+	assert.Equal(t, FunctionStats{Name: "fib", Declaration: lexer.Position{Line: 1}, Calls: 2, Flat: 3 * time.Millisecond, Cum: 3 * time.Millisecond}, functions[0])
+}
+
+func profileProgram(t *testing.T, program string) *Profiler {
+	p := New("test.lox")
+	i := interpreter.New(zap.NewNop().Sugar(), interpreter.WithCallHook(p), interpreter.WithStdout(io.Discard))
+	p.Start()
+	require.NoError(t, i.Run(program))
+	p.Stop()
+	return p
+}
+
+func TestProfileProgram(t *testing.T) {
+	p := profileProgram(t, `fun fib(n) {
+  if (n < 2) return n;
+  return fib(n - 1) + fib(n - 2);
+}
+class Counter {
+  init() { this.n = 0; }
+  add() { this.n = this.n + fib(3); }
+}
+var c = Counter();
+for (var i = 0; i < 5; i = i + 1) c.add();
+print clock();`)
+
+	calls := map[string]int{}
+	for _, fn := range p.Functions() {
+		calls[fn.Name] = fn.Calls
+		assert.LessOrEqual(t, fn.Flat, fn.Cum, "expected flat time of %s to be within its cumulative time", fn.Name)
+	}
+	assert.Equal(t, map[string]int{"[script]": 1, "Counter": 1, "Counter.add": 5, "fib": 25, "clock": 1}, calls)
+
+	report := &bytes.Buffer{}
+	p.WriteReport(report)
+	assert.Contains(t, report.String(), "Flat profile")
+	assert.Contains(t, report.String(), "Cumulative profile")
+	assert.Contains(t, report.String(), "Counter.add:7:3\n")
+}
+
+func TestFunctionsOfTheSameNameAreProfiledSeparately(t *testing.T) {
+	p := profileProgram(t, `fun a() {
+  fun helper() {}
+  helper();
+}
+fun b() {
+  fun helper() {}
+  helper();
+  helper();
+}
+a();
+b();`)
+
+	calls := map[string]int{}
+	for _, fn := range p.Functions() {
+		calls[fn.Label()] = fn.Calls
+	}
+	assert.Equal(t, map[string]int{"[script]": 1, "a:1:5": 1, "b:5:5": 1, "helper:2:7": 1, "helper:6:7": 2}, calls)
+
+	report := &bytes.Buffer{}
+	p.WriteReport(report)
+	assert.Contains(t, report.String(), "1  helper:2:7\n")
+	assert.Contains(t, report.String(), "2  helper:6:7\n")
+
+	out := &bytes.Buffer{}
+	require.NoError(t, p.WritePprof(out))
+	gz, err := gzip.NewReader(out)
+	require.NoError(t, err)
+	raw, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	for _, s := range []string{"helper:2:7", "helper:6:7"} {
+		assert.True(t, bytes.Contains(raw, []byte(s)), "expected '%s' in the string table", s)
+	}
+}
+
+func TestWritePprof(t *testing.T) {
+	p := profileProgram(t, `fun f() {} fun g() { f(); } g();`)
+
+	out := &bytes.Buffer{}
+	require.NoError(t, p.WritePprof(out))
+	gz, err := gzip.NewReader(out)
+	require.NoError(t, err)
+	raw, err := io.ReadAll(gz)
+	require.NoError(t, err)
+
+	for _, s := range []string{"calls", "count", "wall", "nanoseconds", "test.lox", "[script]", "f", "g"} {
+		assert.True(t, bytes.Contains(raw, []byte(s)), "expected '%s' in the string table", s)
+	}
+}
@@ -27,6 +27,7 @@ func (r *Resolver) VisitBlock(b *parser.Block) error {
 	if err := r.ResolveNodes(b.Statements); err != nil {
 		return err
 	}
+	r.scopeSizes[b] = len(r.Scopes[0])
 	return r.endScope()
 }
 
@@ -51,7 +52,7 @@ func (r *Resolver) VisitUnary(u *parser.Unary) error {
 
 func (r *Resolver) VisitVariable(v *parser.Variable) error {
 	if len(r.Scopes) > 0 {
-		if defined, declared := r.Scopes[0][v.TokenName]; declared && !defined {
+		if b, declared := r.Scopes[0][v.TokenName]; declared && !b.Defined {
 			return fmt.Errorf("can't read local variable '%s' in its own initializer", v.TokenName)
 		}
 	}
@@ -73,7 +74,7 @@ func (r *Resolver) VisitVarStmt(v *parser.VarStmt) error {
 		}
 	}
 
-	r.declare(v.Name)
+	r.declare(v, v.Name, v.Position())
 	if v.Initializer != nil {
 		err := r.resolve(v.Initializer)
 		if err != nil {
@@ -109,6 +110,25 @@ func (r *Resolver) VisitWhileStmt(w *parser.WhileStmt) error {
 	return r.resolve(w.Body)
 }
 
+// VisitForInStmt resolves the iterable of a for-in loop outside of the loop, and its variable in a scope of its
+// own around the body, which is created afresh on each iteration
+func (r *Resolver) VisitForInStmt(f *parser.ForInStmt) error {
+	if err := r.resolve(f.Iterable); err != nil {
+		return err
+	}
+	if err := r.beginScope(); err != nil {
+		return err
+	}
+	if err := r.resolve(f.Variable); err != nil {
+		return err
+	}
+	if err := r.resolve(f.Body); err != nil {
+		return err
+	}
+	r.scopeSizes[f] = len(r.Scopes[0])
+	return r.endScope()
+}
+
 func (r *Resolver) VisitCallExpr(f *parser.CallExpr) error {
 	err := r.resolve(f.Callee)
 	if err != nil {
@@ -124,7 +144,7 @@ func (r *Resolver) VisitCallExpr(f *parser.CallExpr) error {
 }
 
 func (r *Resolver) VisitFunctionDeclaration(f *parser.FunctionDeclaration) error {
-	r.declare(f.Name)
+	r.declare(f, f.Name, f.Position())
 	r.define(f.Name)
 	if f.Name == "init" {
 		return r.resolveFunction(f, FT_INITIALIZER)
@@ -141,10 +161,37 @@ func (r *Resolver) VisitReturnStmt(rs *parser.ReturnStmt) error {
 			return fmt.Errorf("can't return a value from the initializer")
 		}
 	}
-	if rs.Expression != nil {
-		return r.resolve(rs.Expression)
+	if rs.Expression == nil {
+		return nil
 	}
-	return nil
+	if _, exists := r.valueReturns[r.currentDeclaration]; !exists {
+		r.valueReturns[r.currentDeclaration] = rs
+	}
+	// A call whose result is returned directly is in tail position, as nothing is left for the function to do
+	// after it. Initializers always return 'this', so never return a call.
+	expr := rs.Expression
+	for g, isGrouping := expr.(*parser.Grouping); isGrouping; g, isGrouping = expr.(*parser.Grouping) {
+		expr = g.Expression
+	}
+	if call, isCall := expr.(*parser.CallExpr); isCall {
+		r.tailCalls[rs] = call
+	}
+	return r.resolve(rs.Expression)
+}
+
+// VisitYieldStmt makes the function the yield is within a generator
+func (r *Resolver) VisitYieldStmt(y *parser.YieldStmt) error {
+	switch r.currentFunction {
+	case FT_NONE:
+		return fmt.Errorf("can't yield outside of a function")
+	case FT_INITIALIZER:
+		return fmt.Errorf("can't yield from the initializer")
+	}
+	r.generators[r.currentDeclaration] = true
+	if y.Expression == nil {
+		return nil
+	}
+	return r.resolve(y.Expression)
 }
 
 // VisitGetExpr implements parser.Visitor.
@@ -152,12 +199,26 @@ func (r *Resolver) VisitGetExpr(g *parser.GetExpr) error {
 	return r.resolve(g.Instance)
 }
 
-// VisitClassDeclaration declares and defines a class from a ClassDeclaration node
+// VisitClassDeclaration declares and defines a class or trait from a ClassDeclaration node
 func (r *Resolver) VisitClassDeclaration(c *parser.ClassDeclaration) error {
-	r.declare(c.Name)
+	r.declare(c, c.Name, c.Position())
 	r.define(c.Name)
+	// Classes may be declared within the methods of another, whose kind is restored after them
+	enclosingClass := r.currentClass
 	r.currentClass = CT_CLASS
-	defer func() { r.currentClass = CT_NONE }()
+	if c.Trait {
+		r.currentClass = CT_TRAIT
+	}
+	defer func() { r.currentClass = enclosingClass }()
+
+	for _, t := range c.Traits {
+		if t.TokenName == c.Name {
+			return fmt.Errorf("'%s' can't include itself as a trait", c.Name)
+		}
+		if err := r.resolve(t); err != nil {
+			return err
+		}
+	}
 
 	if c.SuperClass != nil {
 		r.currentClass = CT_SUBCLASS
@@ -172,22 +233,23 @@ func (r *Resolver) VisitClassDeclaration(c *parser.ClassDeclaration) error {
 			return err
 		}
 		defer r.endScope()
-		r.Scopes[0]["super"] = true
+		r.define("super")
 	}
 
 	if err := r.beginScope(); err != nil {
 		return err
 	}
 	defer r.endScope()
-	r.Scopes[0]["this"] = true
+	r.define("this")
 
 	for _, method := range c.Methods {
 		fd, ok := method.(*parser.FunctionDeclaration)
 		if !ok {
 			return fmt.Errorf("expected function declaration, but got '%v'", method)
 		}
+		// 'this' within a class method is the class it was called on
 		ft := FT_METHOD
-		if fd.Name == "init" {
+		if fd.Name == "init" && !fd.Static && !fd.Setter {
 			ft = FT_INITIALIZER
 		}
 		if err := r.resolveFunction(fd, ft); err != nil {
@@ -210,7 +272,7 @@ func (r *Resolver) VisitSetExpr(s *parser.SetExpr) error {
 }
 
 func (r *Resolver) VisitThisExpr(t *parser.ThisExpr) error {
-	if r.currentClass != CT_CLASS {
+	if r.currentClass == CT_NONE {
 		return fmt.Errorf("'this' cannot be used outside of a class")
 	}
 	r.resolveLocal(t, t.Keyword.Lexeme)
@@ -218,6 +280,11 @@ func (r *Resolver) VisitThisExpr(t *parser.ThisExpr) error {
 }
 
 func (r *Resolver) VisitSuperExpr(s *parser.SuperExpr) error {
+	// A trait's methods are closed over where the trait is declared, so they have no superclass whichever class
+	// they're included in
+	if r.currentClass == CT_TRAIT {
+		return fmt.Errorf("'super' can't be used in a trait")
+	}
 	if r.currentClass != CT_SUBCLASS {
 		return fmt.Errorf("'super' can only be used in a subclass")
 	}
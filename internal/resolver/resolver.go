@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/levpaul/glocks/internal/lexer"
 	"github.com/levpaul/glocks/internal/parser"
 )
 
@@ -24,41 +25,86 @@ const (
 	CT_NONE ClassType = iota
 	CT_CLASS
 	CT_SUBCLASS
+	CT_TRAIT
 )
 
-// Scope is a map of variable names to whether they have been defined or not
-type Scope map[string]bool
+// Binding is a name declared within a scope
+type Binding struct {
+	// Defined is false between a variable being declared and its initializer being resolved
+	Defined bool
+	// Pos is where the name was declared, it is unknown for the implicit 'this' and 'super' bindings
+	Pos lexer.Position
+	// Slot is the index of the variable within its scope's environment, assigned in order of declaration
+	Slot int
+}
+
+// Scope is a map of variable names to their bindings
+type Scope map[string]*Binding
+
+// Local is where a local variable is found at runtime: the number of environments out from the current one,
+// and the slot within that environment
+type Local struct {
+	Depth int
+	Slot  int
+}
 
 // Resolver is responsible for resolving variable names to their scope. It walks the entire AST
 // before execution to resolve variable names to do so. Essentially, it is a stack of scopes, which
 // correlates directly to the environment stack in the interpreter. The resolver analyzes each variable
 // declaration and assigns it a depth in the scope chain, which is used to resolve variables at runtime
 // by the evaluation component of the interpreter by using the distance to the variable's correct
-// scope/environment.
+// scope/environment. Variables are also assigned a slot within their scope, so the interpreter can store them
+// in an indexed environment rather than looking them up by name. The outermost scope holds globals, which
+// are looked up by name instead.
 type Resolver struct {
 	// Scopes is a stack of scopes, with the current scope being the top of the stack
 	Scopes []Scope
 	// currentFunction is the type of function that is currently being resolved
 	currentFunction FunctionType
-	// locals is a map of nodes to their depth in the scope chain
-	locals map[parser.Node]int
+	// locals is a map of nodes to the local variable they access or declare. Nodes for globals are absent.
+	locals map[parser.Node]Local
 	// currentClass is the type of class that is currently being resolved, used for invalid uses of 'this'
 	currentClass ClassType
+	// bindings is a map of nodes to the binding they resolved to
+	bindings map[parser.Node]*Binding
+	// scopeSizes is a map of blocks and functions to the number of variables declared in their scope
+	scopeSizes map[parser.Node]int
+	// tailCalls is a map of return statements to the call they return the result of
+	tailCalls map[*parser.ReturnStmt]*parser.CallExpr
+	// currentDeclaration is the function currently being resolved, or nil outside of any function
+	currentDeclaration *parser.FunctionDeclaration
+	// generators are the functions which yield
+	generators map[*parser.FunctionDeclaration]bool
+	// valueReturns are the first statement returning a value within each function
+	valueReturns map[*parser.FunctionDeclaration]*parser.ReturnStmt
 }
 
 func NewResolver() *Resolver {
 	return &Resolver{
 		Scopes:          []Scope{{}},
-		locals:          make(map[parser.Node]int),
+		locals:          make(map[parser.Node]Local),
+		bindings:        make(map[parser.Node]*Binding),
+		scopeSizes:      make(map[parser.Node]int),
+		tailCalls:       make(map[*parser.ReturnStmt]*parser.CallExpr),
+		generators:      make(map[*parser.FunctionDeclaration]bool),
+		valueReturns:    make(map[*parser.FunctionDeclaration]*parser.ReturnStmt),
 		currentFunction: FT_NONE,
 		currentClass:    CT_NONE,
 	}
 }
 
 // resolve resolves a single node by calling Accept on the node, which in turn calls the appropriate Visit method
-// of the passed Node
+// of the passed Node. Errors are given the position of the innermost node they occurred in.
 func (r *Resolver) resolve(node parser.Node) error {
-	return node.Accept(r)
+	err := node.Accept(r)
+	if err == nil {
+		return nil
+	}
+	var posErr *lexer.PositionError
+	if errors.As(err, &posErr) {
+		return err
+	}
+	return &lexer.PositionError{Pos: node.Position(), Err: err}
 }
 
 // resolveNodes resolves a slice of nodes by calling resolve on each node
@@ -87,22 +133,35 @@ func (r *Resolver) endScope() error {
 	return nil
 }
 
-// declare declares a variable in the current scope, but does not define it
-func (r *Resolver) declare(name string) {
+// declare declares a variable in the current scope, but does not define it. The declaring node is recorded so
+// the interpreter knows which slot to define a local variable in.
+func (r *Resolver) declare(node parser.Node, name string, pos lexer.Position) {
 	if len(r.Scopes) == 0 {
 		return
 	}
 
-	r.Scopes[0][name] = false
+	// Redeclaring a name, which is allowed for functions and classes, reuses the slot of the original
+	slot := len(r.Scopes[0])
+	if b, exists := r.Scopes[0][name]; exists {
+		slot = b.Slot
+	}
+	r.Scopes[0][name] = &Binding{Pos: pos, Slot: slot}
+	if node != nil && len(r.Scopes) > 1 {
+		r.SetLocal(node, Local{Depth: 0, Slot: slot})
+	}
 }
 
-// define defines a variable in the current scope marking it as true in the scope map
+// define defines a variable in the current scope marking it as defined in the scope map
 func (r *Resolver) define(name string) {
 	if len(r.Scopes) == 0 {
 		return
 	}
 
-	r.Scopes[0][name] = true
+	if b, exists := r.Scopes[0][name]; exists {
+		b.Defined = true
+		return
+	}
+	r.Scopes[0][name] = &Binding{Defined: true, Slot: len(r.Scopes[0])}
 }
 
 // resolveLocal walks through the scopes stack, from narrowest to widest to find the 'distance' to resolution
@@ -110,8 +169,11 @@ func (r *Resolver) resolveLocal(node parser.Node, name string) {
 	// This is different to the book as Java indexes Stacks with 0 being the bottom of the stack
 	// whereas here I'm using the zero index as the top of the stack
 	for i, scope := range r.Scopes {
-		if _, exists := scope[name]; exists {
-			r.SetDepth(node, i)
+		if b, exists := scope[name]; exists {
+			if i < len(r.Scopes)-1 { // globals are looked up by name
+				r.SetLocal(node, Local{Depth: i, Slot: b.Slot})
+			}
+			r.bindings[node] = b
 			return
 		}
 	}
@@ -119,30 +181,78 @@ func (r *Resolver) resolveLocal(node parser.Node, name string) {
 
 // resolveFunction resolves a function declaration, including its parameters and body
 func (r *Resolver) resolveFunction(f *parser.FunctionDeclaration, ft FunctionType) error {
-	enclosingFunction := r.currentFunction
-	r.currentFunction = ft
-	defer func() { r.currentFunction = enclosingFunction }()
+	enclosingFunction, enclosingDeclaration := r.currentFunction, r.currentDeclaration
+	r.currentFunction, r.currentDeclaration = ft, f
+	defer func() { r.currentFunction, r.currentDeclaration = enclosingFunction, enclosingDeclaration }()
 
 	if err := r.beginScope(); err != nil {
 		return err
 	}
-	for _, p := range f.Params {
-		r.declare(p)
-		r.define(p)
+	// Parameters take the first slots of the function's environment, in order. Their defaults are evaluated in the
+	// function's environment as each is bound, so can refer to the parameters before them.
+	for idx, p := range f.Params {
+		if _, exists := r.Scopes[0][p.Lexeme]; exists {
+			return &lexer.PositionError{Pos: p.Position(), Err: fmt.Errorf("already exists a parameter with name='%s'", p.Lexeme)}
+		}
+		if def := f.Default(idx); def != nil {
+			if err := r.resolve(def); err != nil {
+				return err
+			}
+		}
+		r.declare(nil, p.Lexeme, p.Position())
+		r.define(p.Lexeme)
 	}
 	if err := r.ResolveNodes(f.Body); err != nil {
 		return err
 	}
+	// A generator's return only ends it, so it has no value to return
+	if rs, returnsValue := r.valueReturns[f]; returnsValue && r.generators[f] {
+		return &lexer.PositionError{Pos: rs.Position(), Err: fmt.Errorf("can't return a value from generator '%s'", f.Name)}
+	}
+	r.scopeSizes[f] = len(r.Scopes[0])
 	return r.endScope()
 }
 
-func (r *Resolver) SetDepth(node parser.Node, depth int) {
-	r.locals[node] = depth
+// IsGenerator reports whether a function yields, so that calling it returns a generator rather than running it
+func (r *Resolver) IsGenerator(f *parser.FunctionDeclaration) bool {
+	return r.generators[f]
+}
+
+// SetLocal records where the local variable accessed or declared by a node is found at runtime
+func (r *Resolver) SetLocal(node parser.Node, local Local) {
+	r.locals[node] = local
+}
+
+// DeleteLocal forgets where the local variable accessed by a node is found, for nodes which won't run again
+func (r *Resolver) DeleteLocal(node parser.Node) {
+	delete(r.locals, node)
 }
 
-func (r *Resolver) GetLocal(node parser.Node) (int, error) {
-	if depth, exists := r.locals[node]; exists {
-		return depth, nil
+// Declaration returns the position of the declaration a variable node was resolved to. Names which are not
+// declared in Lox code, such as native functions, have no declaration.
+func (r *Resolver) Declaration(node parser.Node) (lexer.Position, bool) {
+	b, exists := r.bindings[node]
+	if !exists || b.Pos == (lexer.Position{}) {
+		return lexer.Position{}, false
 	}
-	return 0, fmt.Errorf("could not find local variable for node %v", node)
+	return b.Pos, true
+}
+
+// GetLocal returns where the local variable accessed or declared by a node is found at runtime, which is not
+// found for globals
+func (r *Resolver) GetLocal(node parser.Node) (Local, bool) {
+	local, exists := r.locals[node]
+	return local, exists
+}
+
+// ScopeSize returns the number of slots needed by the environment of a block or function
+func (r *Resolver) ScopeSize(node parser.Node) int {
+	return r.scopeSizes[node]
+}
+
+// TailCall returns the call a return statement returns the result of, if it returns a call. The call is in tail
+// position, so the interpreter can make it in place of the function returning rather than nesting within it.
+func (r *Resolver) TailCall(rs *parser.ReturnStmt) (*parser.CallExpr, bool) {
+	call, exists := r.tailCalls[rs]
+	return call, exists
 }
@@ -0,0 +1,42 @@
+package testrunner
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// expectOutput matches `// expect: <output line>`
+	expectOutput = regexp.MustCompile(`// expect: ?(.*)$`)
+	// expectRuntimeError matches `// expect runtime error: <message>`
+	expectRuntimeError = regexp.MustCompile(`// expect runtime error: ?(.*)$`)
+	// expectStaticError matches the compile error annotations of the craftinginterpreters test suite, such as
+	// `// Error at 'x': <message>` and `// [line 3] Error: <message>`
+	expectStaticError = regexp.MustCompile(`// (?:\[line \d+\] )?Error.*$`)
+)
+
+// expectations are the results a test script is annotated with
+type expectations struct {
+	// output is each line the script is expected to print, in order
+	output []string
+	// runtimeError is a substring of the runtime error expected, if any
+	runtimeError string
+	// staticError is true when the script is expected to fail scanning, parsing or resolving. As error messages
+	// differ between Lox implementations, only the failure itself is checked.
+	staticError bool
+}
+
+// parseExpectations reads the expectation comments from a test script
+func parseExpectations(source string) expectations {
+	var exp expectations
+	for _, line := range strings.Split(source, "\n") {
+		if m := expectOutput.FindStringSubmatch(line); m != nil {
+			exp.output = append(exp.output, strings.TrimRight(m[1], "\r"))
+		} else if m = expectRuntimeError.FindStringSubmatch(line); m != nil {
+			exp.runtimeError = strings.TrimRight(m[1], "\r")
+		} else if expectStaticError.MatchString(line) {
+			exp.staticError = true
+		}
+	}
+	return exp
+}
@@ -0,0 +1,101 @@
+package testrunner
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Report writes a human readable line for each result, with the details of any failures, followed by a summary
+func Report(w io.Writer, results []Result) {
+	failed := 0
+	for _, r := range results {
+		if r.Passed() {
+			fmt.Fprintf(w, "PASS %s (%s)\n", r.File, r.Duration.Round(time.Microsecond))
+			continue
+		}
+		failed++
+		fmt.Fprintf(w, "FAIL %s (%s)\n", r.File, r.Duration.Round(time.Microsecond))
+		for _, f := range r.Failures {
+			fmt.Fprintf(w, "    %s\n", strings.ReplaceAll(f, "\n", "\n    "))
+		}
+	}
+	fmt.Fprintf(w, "\n%d passed, %d failed\n", len(results)-failed, failed)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     float64          `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      float64         `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Details string `xml:",chardata"`
+}
+
+// WriteJUnit writes results as JUnit XML, with a test suite for each directory of scripts
+func WriteJUnit(w io.Writer, results []Result) error {
+	report := junitTestSuites{}
+	suiteIdx := map[string]int{}
+	for _, r := range results {
+		dir := filepath.Dir(r.File)
+		idx, exists := suiteIdx[dir]
+		if !exists {
+			idx = len(report.Suites)
+			suiteIdx[dir] = idx
+			report.Suites = append(report.Suites, junitTestSuite{Name: dir})
+		}
+		suite := &report.Suites[idx]
+
+		tc := junitTestCase{
+			Name:      filepath.Base(r.File),
+			ClassName: dir,
+			Time:      r.Duration.Seconds(),
+		}
+		if !r.Passed() {
+			tc.Failure = &junitFailure{
+				Message: strings.SplitN(r.Failures[0], "\n", 2)[0],
+				Details: strings.Join(r.Failures, "\n"),
+			}
+			suite.Failures++
+			report.Failures++
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+		suite.Tests++
+		suite.Time += tc.Time
+		report.Tests++
+		report.Time += tc.Time
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,210 @@
+package testrunner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/levpaul/glocks/internal/interpreter"
+	"go.uber.org/zap"
+)
+
+// Result is the outcome of running a single test script
+type Result struct {
+	File     string
+	Duration time.Duration
+	// Failures describes each way the script didn't meet its expectations, it is empty when the test passed
+	Failures []string
+}
+
+// Passed reports whether the script met all of its expectations
+func (r Result) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// RunDir finds every .lox file within dir and its subdirectories, and runs them with up to parallelism files
+// running at once, each for at most timeout. Results are returned in order of file path.
+func RunDir(dir string, parallelism int, timeout time.Duration, opts ...interpreter.Option) ([]Result, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && filepath.Ext(path) == ".lox" {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	results := make([]Result, len(files))
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < parallelism; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				results[idx] = RunFile(files[idx], timeout, opts...)
+			}
+		}()
+	}
+	for idx := range files {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].File < results[j].File })
+	return results, nil
+}
+
+// RunFile runs a single test script in a fresh interpreter, configured by opts, and checks it against its
+// expectation comments. A script still running after timeout is stopped and fails, and a timeout of zero or less
+// lets scripts run for as long as they take.
+func RunFile(path string, timeout time.Duration, opts ...interpreter.Option) Result {
+	res := Result{File: path}
+	source, err := os.ReadFile(path)
+	if err != nil {
+		res.Failures = append(res.Failures, fmt.Sprintf("failed to read file: %v", err))
+		return res
+	}
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	output, runErr := runSource(ctx, string(source), opts)
+	res.Duration = time.Since(start)
+	var panicErr *panicError
+	switch {
+	case errors.Is(runErr, context.DeadlineExceeded):
+		res.Failures = append(res.Failures, fmt.Sprintf("timed out after %s", timeout))
+		return res
+	case errors.As(runErr, &panicErr):
+		res.Failures = append(res.Failures, panicErr.Error())
+		return res
+	}
+	res.Failures = check(parseExpectations(string(source)), output, runErr)
+	return res
+}
+
+// panicError is the error of a run which panicked, so that a bug in the interpreter fails only the script which
+// found it rather than the whole test run
+type panicError struct {
+	value any
+}
+
+func (p *panicError) Error() string {
+	return fmt.Sprintf("interpreter panicked: %v", p.value)
+}
+
+// runSource runs a program until it finishes or ctx is done, returning everything it printed along with any error
+// it failed with, including a panicError if it panicked
+func runSource(ctx context.Context, source string, opts []interpreter.Option) (output string, err error) {
+	out := &strings.Builder{}
+	defer func() {
+		if r := recover(); r != nil {
+			output, err = out.String(), &panicError{value: r}
+		}
+	}()
+	i := interpreter.New(zap.NewNop().Sugar(), append([]interpreter.Option{interpreter.WithStdout(out)}, opts...)...)
+	err = i.RunContext(ctx, source)
+	return out.String(), err
+}
+
+// check compares the output and error of a run against the expectations, returning a description of each failure
+func check(exp expectations, output string, runErr error) []string {
+	var failures []string
+	var runtimeErr *interpreter.RuntimeError
+	isRuntimeErr := errors.As(runErr, &runtimeErr)
+
+	switch {
+	case exp.staticError && (runErr == nil || isRuntimeErr):
+		failures = append(failures, "expected a compile error, but the program compiled")
+	case !exp.staticError && runErr != nil && !isRuntimeErr:
+		failures = append(failures, fmt.Sprintf("unexpected compile error: %v", runErr))
+	case exp.runtimeError != "" && !isRuntimeErr:
+		failures = append(failures, fmt.Sprintf("expected runtime error '%s', but the program completed", exp.runtimeError))
+	case exp.runtimeError != "" && !strings.Contains(runtimeErr.Err.Error(), exp.runtimeError):
+		failures = append(failures, fmt.Sprintf("expected runtime error '%s', got '%v'", exp.runtimeError, runtimeErr.Err))
+	case exp.runtimeError == "" && isRuntimeErr:
+		failures = append(failures, fmt.Sprintf("unexpected runtime error: %v", runtimeErr.Err))
+	}
+
+	var actual []string
+	if output != "" {
+		actual = strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	}
+	if !equal(exp.output, actual) {
+		failures = append(failures, "output differs:\n"+diff(exp.output, actual))
+	}
+	return failures
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// diff returns a line diff turning expected into actual, with lines prefixed by '-' only appearing in expected,
+// '+' only appearing in actual and ' ' in both
+func diff(expected, actual []string) string {
+	// lcs[i][j] is the length of the longest common subsequence of expected[i:] and actual[j:]
+	lcs := make([][]int, len(expected)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(actual)+1)
+	}
+	for i := len(expected) - 1; i >= 0; i-- {
+		for j := len(actual) - 1; j >= 0; j-- {
+			if expected[i] == actual[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	b := strings.Builder{}
+	i, j := 0, 0
+	for i < len(expected) || j < len(actual) {
+		switch {
+		case i < len(expected) && j < len(actual) && expected[i] == actual[j]:
+			b.WriteString("  " + expected[i] + "\n")
+			i++
+			j++
+		case j == len(actual) || (i < len(expected) && lcs[i+1][j] >= lcs[i][j+1]):
+			b.WriteString("- " + expected[i] + "\n")
+			i++
+		default:
+			b.WriteString("+ " + actual[j] + "\n")
+			j++
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
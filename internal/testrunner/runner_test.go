@@ -0,0 +1,86 @@
+package testrunner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/levpaul/glocks/internal/interpreter"
+	"github.com/levpaul/glocks/internal/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPassingScripts(t *testing.T) {
+	results, err := RunDir("testdata/pass", 4, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+	for _, r := range results {
+		assert.True(t, r.Passed(), "expected %s to pass, failures: %v", r.File, r.Failures)
+	}
+}
+
+func TestFailingScripts(t *testing.T) {
+	results, err := RunDir("testdata/fail", 2, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	assert.Equal(t, "testdata/fail/missing_error.lox", results[0].File)
+	assert.Equal(t, []string{"expected runtime error 'something went wrong', but the program completed"}, results[0].Failures)
+
+	assert.Equal(t, "testdata/fail/wrong_output.lox", results[1].File)
+	assert.Equal(t, []string{"output differs:\n  one\n- 2\n+ two\n  three"}, results[1].Failures)
+
+	out := &strings.Builder{}
+	Report(out, results)
+	assert.Contains(t, out.String(), "FAIL testdata/fail/wrong_output.lox")
+	assert.Contains(t, out.String(), "\n    - 2\n    + two\n")
+	assert.True(t, strings.HasSuffix(out.String(), "\n0 passed, 2 failed\n"))
+}
+
+func TestScriptTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "loop.lox")
+	require.NoError(t, os.WriteFile(path, []byte("print 1; // expect: 1\nwhile (true) {}\n"), 0o644))
+
+	res := RunFile(path, 50*time.Millisecond)
+	assert.Equal(t, []string{"timed out after 50ms"}, res.Failures)
+}
+
+// panicHook panics before the statements on its line, standing in for a bug in the interpreter
+type panicHook struct {
+	line int
+}
+
+func (h panicHook) BeforeStatement(stmt parser.Node) error {
+	if stmt.Position().Line == h.line {
+		panic("boom")
+	}
+	return nil
+}
+
+func TestScriptPanic(t *testing.T) {
+	results, err := RunDir("testdata/pass", 2, time.Minute, interpreter.WithStatementHook(panicHook{line: 1}))
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+	for _, r := range results {
+		if strings.HasSuffix(r.File, "static_error.lox") {
+			assert.True(t, r.Passed(), "a script which fails to compile never runs a statement")
+			continue
+		}
+		assert.Equal(t, []string{"interpreter panicked: boom"}, r.Failures, r.File)
+	}
+}
+
+func TestJUnitReport(t *testing.T) {
+	results := []Result{
+		{File: "a/pass.lox"},
+		{File: "a/fail.lox", Failures: []string{"output differs:\n- 1\n+ 2"}},
+	}
+	out := &strings.Builder{}
+	require.NoError(t, WriteJUnit(out, results))
+	assert.Contains(t, out.String(), `<testsuites tests="2" failures="1" time="0">`)
+	assert.Contains(t, out.String(), `<testcase name="pass.lox" classname="a" time="0"></testcase>`)
+	assert.Contains(t, out.String(), `<failure message="output differs:">output differs:&#xA;- 1&#xA;+ 2</failure>`)
+}
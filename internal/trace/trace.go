@@ -0,0 +1,193 @@
+// Package trace logs the execution of a Lox program: each statement, function call and return, and variable
+// assignment, either as indented text for people or as JSON lines for tools.
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/levpaul/glocks/internal/domain"
+	"github.com/levpaul/glocks/internal/lexer"
+	"github.com/levpaul/glocks/internal/parser"
+)
+
+// Format is how trace events are written
+type Format string
+
+const (
+	// Text writes an event per line, indented by the call depth it occurred at
+	Text Format = "text"
+	// JSON writes each event as a JSON object on its own line
+	JSON Format = "json"
+)
+
+// ParseFormat validates the name of a format, such as one given on the command line
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(name); f {
+	case Text, JSON:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown trace format '%s', expected '%s' or '%s'", name, Text, JSON)
+}
+
+// Event kinds
+const (
+	EventStatement = "statement"
+	EventCall      = "call"
+	EventReturn    = "return"
+	EventAssign    = "assign"
+)
+
+// Event is a single traced step of execution. Fields which don't apply to an event's kind are left empty.
+type Event struct {
+	Event string `json:"event"`
+	// Depth is the number of calls in progress when the event occurred
+	Depth  int `json:"depth"`
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+	// Node is the type of statement executed, or the statement which assigned a variable
+	Node string `json:"node,omitempty"`
+	// Source is the line of source code a statement is on
+	Source string `json:"source,omitempty"`
+	// Name is the function called or returned from, or the variable assigned
+	Name  string   `json:"name,omitempty"`
+	Args  []string `json:"args,omitempty"`
+	Value string   `json:"value,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+// Tracer is an interpreter.StatementHook, interpreter.CallHook and interpreter.AssignHook which writes an event
+// for everything it is notified of. Errors writing the trace stop the program.
+type Tracer struct {
+	w      io.Writer
+	format Format
+	// source is the program's source split into lines, used to show the code of each statement
+	source []string
+	depth  int
+	// calls are the names of calls in progress, so their returns can be named too
+	calls []string
+	err   error
+}
+
+// New creates a Tracer writing to w. The source of the program is used to show the code of each statement, and may
+// be empty when it isn't known, such as in the REPL.
+func New(w io.Writer, format Format, source string) *Tracer {
+	return &Tracer{w: w, format: format, source: strings.Split(source, "\n")}
+}
+
+// BeforeStatement implements interpreter.StatementHook
+func (t *Tracer) BeforeStatement(stmt parser.Node) error {
+	if _, isBlock := stmt.(*parser.Block); isBlock || stmt == nil {
+		return t.err // the statements within the block are traced instead
+	}
+	pos := stmt.Position()
+	t.write(Event{
+		Event:  EventStatement,
+		Line:   pos.Line,
+		Column: pos.Column,
+		Node:   nodeName(stmt),
+		Source: t.sourceLine(pos.Line),
+	})
+	return t.err
+}
+
+// EnterCall implements interpreter.CallHook
+func (t *Tracer) EnterCall(name string, _ lexer.Position, args []domain.Value) {
+	e := Event{Event: EventCall, Name: name, Args: make([]string, len(args))}
+	for idx, a := range args {
+		e.Args[idx] = formatValue(a)
+	}
+	t.write(e)
+	t.calls = append(t.calls, name)
+	t.depth++
+}
+
+// ExitCall implements interpreter.CallHook
+func (t *Tracer) ExitCall(result domain.Value, err error) {
+	t.depth--
+	name := t.calls[len(t.calls)-1]
+	t.calls = t.calls[:len(t.calls)-1]
+
+	e := Event{Event: EventReturn, Name: name}
+	if err != nil {
+		e.Error = err.Error()
+	} else {
+		e.Value = formatValue(result)
+	}
+	t.write(e)
+}
+
+// Assign implements interpreter.AssignHook
+func (t *Tracer) Assign(node parser.Node, name string, value domain.Value) {
+	pos := node.Position()
+	t.write(Event{
+		Event:  EventAssign,
+		Line:   pos.Line,
+		Column: pos.Column,
+		Node:   nodeName(node),
+		Name:   name,
+		Value:  formatValue(value),
+	})
+}
+
+// write writes an event in the tracer's format, remembering the first error so it can stop the program
+func (t *Tracer) write(e Event) {
+	if t.err != nil {
+		return
+	}
+	e.Depth = t.depth
+	if t.format == JSON {
+		t.err = json.NewEncoder(t.w).Encode(e)
+		return
+	}
+	_, t.err = fmt.Fprintln(t.w, strings.Repeat("  ", t.depth)+formatText(e))
+}
+
+func formatText(e Event) string {
+	switch e.Event {
+	case EventStatement:
+		// The source isn't known for code entered into the REPL
+		if e.Source == "" {
+			return fmt.Sprintf("[%d:%d] %s", e.Line, e.Column, e.Node)
+		}
+		return fmt.Sprintf("[%d:%d] %s", e.Line, e.Column, e.Source)
+	case EventCall:
+		return fmt.Sprintf("call %s(%s)", e.Name, strings.Join(e.Args, ", "))
+	case EventReturn:
+		if e.Error != "" {
+			return fmt.Sprintf("return %s failed: %s", e.Name, e.Error)
+		}
+		return fmt.Sprintf("return %s = %s", e.Name, e.Value)
+	case EventAssign:
+		return fmt.Sprintf("[%d:%d] %s = %s", e.Line, e.Column, e.Name, e.Value)
+	}
+	return e.Event
+}
+
+// sourceLine returns the trimmed source of a line, which is numbered from 1
+func (t *Tracer) sourceLine(line int) string {
+	if line < 1 || line > len(t.source) {
+		return ""
+	}
+	return strings.TrimSpace(t.source[line-1])
+}
+
+// nodeName returns the name of a node's type, such as "PrintStmt"
+func nodeName(node parser.Node) string {
+	return reflect.TypeOf(node).Elem().Name()
+}
+
+// formatValue formats a value as Lox code would show it, quoting strings so they can be told apart from other
+// values
+func formatValue(v domain.Value) string {
+	switch val := v.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return fmt.Sprintf("%q", val)
+	}
+	return fmt.Sprint(v)
+}
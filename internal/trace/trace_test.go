@@ -0,0 +1,98 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/levpaul/glocks/internal/interpreter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const program = `fun double(n) {
+  return n * 2;
+}
+var x = "a";
+x = double(3);
+`
+
+func runTraced(t *testing.T, format Format, source string) string {
+	out := &bytes.Buffer{}
+	tr := New(out, format, source)
+	i := interpreter.New(zap.NewNop().Sugar(), interpreter.WithStatementHook(tr), interpreter.WithCallHook(tr),
+		interpreter.WithAssignHook(tr), interpreter.WithStdout(io.Discard))
+	require.NoError(t, i.Run(source))
+	return out.String()
+}
+
+func TestTextTrace(t *testing.T) {
+	expected := `[1:5] fun double(n) {
+[4:5] var x = "a";
+[4:5] x = "a"
+[5:1] x = double(3);
+call double(3)
+  [2:3] return n * 2;
+return double = 6
+[5:1] x = 6
+`
+	assert.Equal(t, expected, runTraced(t, Text, program))
+}
+
+func TestJSONTrace(t *testing.T) {
+	var events []Event
+	for _, line := range strings.Split(strings.TrimSpace(runTraced(t, JSON, program)), "\n") {
+		var e Event
+		require.NoError(t, json.Unmarshal([]byte(line), &e))
+		events = append(events, e)
+	}
+
+	require.Len(t, events, 8)
+	assert.Equal(t, Event{Event: EventStatement, Line: 4, Column: 5, Node: "VarStmt", Source: `var x = "a";`}, events[1])
+	assert.Equal(t, Event{Event: EventAssign, Line: 4, Column: 5, Node: "VarStmt", Name: "x", Value: `"a"`}, events[2])
+	assert.Equal(t, Event{Event: EventCall, Name: "double", Args: []string{"3"}}, events[4])
+	assert.Equal(t, Event{Event: EventStatement, Depth: 1, Line: 2, Column: 3, Node: "ReturnStmt", Source: "return n * 2;"}, events[5])
+	assert.Equal(t, Event{Event: EventReturn, Name: "double", Value: "6"}, events[6])
+}
+
+func TestTraceMethodsAndErrors(t *testing.T) {
+	source := `class A {
+  init(v) { this.v = v; }
+  fail() { return nil + 1; }
+}
+A(1).fail();
+`
+	out := &bytes.Buffer{}
+	tr := New(out, Text, source)
+	i := interpreter.New(zap.NewNop().Sugar(), interpreter.WithCallHook(tr), interpreter.WithStdout(io.Discard))
+	require.Error(t, i.Run(source))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, "call A(1)", lines[0])
+	assert.Equal(t, "return A = A instance", lines[1])
+	assert.Equal(t, "call A.fail()", lines[2])
+	assert.True(t, strings.HasPrefix(lines[3], "return A.fail failed: "), lines[3])
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestWriteErrorStopsProgram(t *testing.T) {
+	tr := New(failingWriter{}, Text, program)
+	i := interpreter.New(zap.NewNop().Sugar(), interpreter.WithStatementHook(tr), interpreter.WithStdout(io.Discard))
+	assert.ErrorContains(t, i.Run(program), "disk full")
+}
+
+func TestParseFormat(t *testing.T) {
+	f, err := ParseFormat("json")
+	require.NoError(t, err)
+	assert.Equal(t, JSON, f)
+	_, err = ParseFormat("xml")
+	assert.Error(t, err)
+}